@@ -0,0 +1,146 @@
+package marketdata
+
+import (
+	"errors"
+	"time"
+)
+
+type Bar struct {
+	Date     time.Time
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	Volume   int64
+	Vwap     float64
+	BarCount int32
+	Complete bool
+}
+
+// BarAggregator builds higher time frame bars from a stream of lower time frame bars
+// or ticks and reports each bar once it is complete. Daily input completes weekly,
+// monthly, quarterly and yearly bars on their last trading day according to Calendar;
+// any other input completes a bar when the first update of the next period arrives.
+// When Precision is set the vwap is rounded to it like the vwap of TickerData. A
+// BarAggregator is not safe for concurrent use, so feed it from a single goroutine.
+type BarAggregator struct {
+	BaseTimeFrame string
+	TimeFrame     string
+	Calendar      *Calendar
+	Precision     *int
+	onBar         func(Bar)
+	bars          chan Bar
+	current       barState
+	currentKey    int64
+}
+
+func NewBarAggregator(baseTimeFrame string, timeFrame string, onBar func(Bar)) (*BarAggregator, error) {
+	if !isSupportedAggregatorTimeFrame(timeFrame) {
+		return nil, errors.New("Time frame " + timeFrame + " is not supported by the bar aggregator.")
+	}
+	return &BarAggregator{BaseTimeFrame: baseTimeFrame, TimeFrame: timeFrame, onBar: onBar}, nil
+}
+
+// NewBarAggregatorWithChannel sends every completed bar on the returned channel, which
+// buffers bufferSize bars. Once the buffer is full, the call that completes a bar blocks
+// until a bar is received, so another goroutine has to drain the channel. Close closes
+// the channel after the last bar.
+func NewBarAggregatorWithChannel(baseTimeFrame string, timeFrame string, bufferSize int) (*BarAggregator, <-chan Bar, error) {
+	agg, err := NewBarAggregator(baseTimeFrame, timeFrame, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	agg.bars = make(chan Bar, bufferSize)
+	return agg, agg.bars, nil
+}
+
+func (agg *BarAggregator) AddBar(date time.Time, open float64, high float64, low float64, close float64, volume int64) {
+	agg.startPeriod(date)
+	if agg.current.count == 0 {
+		agg.current.open = open
+		agg.current.high = high
+		agg.current.low = low
+	}
+	if high > agg.current.high {
+		agg.current.high = high
+	}
+	if low < agg.current.low {
+		agg.current.low = low
+	}
+	agg.current.close = close
+	agg.current.volume = agg.current.volume + volume
+	// Without the constituent trades the typical price stands in for the vwap of a bar.
+	agg.current.notional = agg.current.notional + (high+low+close)/3*float64(volume)
+	agg.current.count++
+	if agg.BaseTimeFrame == "daily" && agg.calendar().isLastDayOfTimeFrame(date, agg.TimeFrame) {
+		agg.Flush()
+	}
+}
+
+func (agg *BarAggregator) AddTick(date time.Time, price float64, size int64) {
+	agg.startPeriod(date)
+	agg.current.add(price, size)
+}
+
+func (agg *BarAggregator) Current() (Bar, bool) {
+	if agg.current.count == 0 {
+		return Bar{}, false
+	}
+	return agg.toBar(false), true
+}
+
+func (agg *BarAggregator) Flush() {
+	if agg.current.count == 0 {
+		return
+	}
+	bar := agg.toBar(true)
+	agg.current = barState{}
+	if agg.onBar != nil {
+		agg.onBar(bar)
+	}
+	if agg.bars != nil {
+		agg.bars <- bar
+	}
+}
+
+func (agg *BarAggregator) Close() {
+	if agg.bars != nil {
+		close(agg.bars)
+		agg.bars = nil
+	}
+}
+
+func (agg *BarAggregator) startPeriod(date time.Time) {
+	key := agg.calendar().periodKey(date, agg.TimeFrame)
+	if agg.current.count > 0 && key != agg.currentKey {
+		agg.Flush()
+	}
+	if agg.current.count == 0 {
+		agg.current.date = date
+		agg.currentKey = key
+	}
+}
+
+func (agg *BarAggregator) toBar(complete bool) Bar {
+	bar := agg.current
+	vwap := bar.vwap()
+	if agg.Precision != nil {
+		vwap = roundPlus(vwap, *agg.Precision)
+	}
+	return Bar{bar.date, bar.open, bar.high, bar.low, bar.close, bar.volume, vwap, bar.count, complete}
+}
+
+func (agg *BarAggregator) calendar() *Calendar {
+	if agg.Calendar == nil {
+		return defaultCalendar
+	}
+	return agg.Calendar
+}
+
+func isSupportedAggregatorTimeFrame(timeFrame string) bool {
+	if timeFrameRank(timeFrame) > 0 {
+		return true
+	}
+	_, err := time.ParseDuration(timeFrame)
+	return err == nil
+}
@@ -0,0 +1,69 @@
+package marketdata
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBarAggregator(t *testing.T) {
+	testCases := []struct {
+		name              string
+		timeFrame         string
+		expectedResultKey string
+		expectedCurrent   string
+	}{
+		{"'Aggregate daily bars into weekly bars'", "weekly", "weekly", "1/2/2017"},
+		{"'Aggregate daily bars into monthly bars'", "monthly", "monthly", "1/2/2017"},
+	}
+	td := getExpectedDailyData()
+	for _, tc := range testCases {
+		var completed TickerData
+		agg, _ := NewBarAggregator("daily", tc.timeFrame, func(bar Bar) {
+			completed.Id = append(completed.Id, int32(len(completed.Date)))
+			completed.Date = append(completed.Date, bar.Date)
+			completed.Open = append(completed.Open, bar.Open)
+			completed.High = append(completed.High, bar.High)
+			completed.Low = append(completed.Low, bar.Low)
+			completed.Close = append(completed.Close, bar.Close)
+			completed.Volume = append(completed.Volume, bar.Volume)
+		})
+		for i := range td.Date {
+			agg.AddBar(td.Date[i], td.Open[i], td.High[i], td.Low[i], td.Close[i], td.Volume[i])
+		}
+		expectedResult, _ := getExpectedHigherTfData(tc.expectedResultKey)
+		current, ok := agg.Current()
+		expectedCurrentDate, _ := time.Parse("1/2/2006", tc.expectedCurrent)
+		if !reflect.DeepEqual(completed, expectedResult) || !ok || !current.Date.Equal(expectedCurrentDate) || current.Complete || current.BarCount != 1 {
+			t.Log("TestBarAggregator test case ", tc.name, " failed. Result was: ", completed, " current: ", current, " but should be: ", expectedResult)
+			t.Fail()
+		}
+	}
+}
+
+func TestBarAggregatorWithTicks(t *testing.T) {
+	agg, bars, _ := NewBarAggregatorWithChannel("tick", "1m", 10)
+	dates := createDates([]string{"2017-01-03T09:30:00Z", "2017-01-03T09:30:20Z", "2017-01-03T09:31:05Z"}, time.RFC3339)
+	prices := []float64{100, 101, 102}
+	for i := range dates {
+		agg.AddTick(dates[i], prices[i], 100)
+	}
+	agg.Flush()
+	agg.Close()
+	result := []Bar{}
+	for bar := range bars {
+		result = append(result, bar)
+	}
+	expectedResult := []Bar{
+		{dates[0], 100, 101, 100, 101, 200, 100.5, 2, true},
+		{dates[2], 102, 102, 102, 102, 100, 102, 1, true},
+	}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Log("TestBarAggregatorWithTicks failed. Result was: ", result, " but should be: ", expectedResult)
+		t.Fail()
+	}
+	if _, err := NewBarAggregator("daily", "fortnightly", nil); err == nil {
+		t.Log("TestBarAggregatorWithTicks should reject unsupported time frames.")
+		t.Fail()
+	}
+}
@@ -0,0 +1,276 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	lockSuffix     = ".lock"
+	takeOverSuffix = ".takeover"
+	journalSuffix  = ".journal"
+)
+
+// atomicFile is written to a temporary file that replaces fileName on commit, so a
+// crash never leaves fileName partly written. It holds the lock of fileName until it
+// is closed, and closing it without a commit discards the temporary file.
+type atomicFile struct {
+	*os.File
+	fileName  string
+	unlock    func()
+	committed bool
+}
+
+func createAtomicFile(fileName string, lockTimeout time.Duration) (*atomicFile, error) {
+	unlock, err := lockFile(fileName, lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if err != nil {
+		unlock()
+		return nil, errors.New("File Write Error: " + err.Error())
+	}
+	return &atomicFile{File: f, fileName: fileName, unlock: unlock}, nil
+}
+
+func (af *atomicFile) commit() error {
+	err := af.File.Sync()
+	if closeErr := af.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(af.File.Name(), af.fileName)
+	}
+	if err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	af.committed = true
+	// A journal of an interrupted append belongs to the replaced file.
+	os.Remove(af.fileName + journalSuffix)
+	syncDir(filepath.Dir(af.fileName))
+	return nil
+}
+
+func (af *atomicFile) Close() error {
+	if !af.committed {
+		af.File.Close()
+		os.Remove(af.File.Name())
+	}
+	af.unlock()
+	return nil
+}
+
+// lockFile creates the advisory lock file of fileName, waiting up to timeout while
+// another writer holds it. The lock file holds the process id of the writer, so a lock
+// left behind by a crashed writer is taken over once its process is gone.
+func lockFile(fileName string, timeout time.Duration) (func(), error) {
+	lockName := fileName + lockSuffix
+	owner := fmt.Sprintf("%d %d\n", os.Getpid(), time.Now().UnixNano())
+	unlock := func() { removeLock(lockName, owner) }
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.WriteString(owner)
+			f.Close()
+			return unlock, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.New("File Lock Error: " + err.Error())
+		}
+		if takeOverStaleLock(lockName, owner) {
+			return unlock, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, errors.New("File Lock Error: " + fileName + " is locked by another writer. Remove " + lockName + " if no writer is running.")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// removeLock removes lockName unless it is no longer the lock written by owner.
+func removeLock(lockName string, owner string) {
+	if data, err := ioutil.ReadFile(lockName); err == nil && string(data) == owner {
+		os.Remove(lockName)
+	}
+}
+
+// takeOverStaleLock replaces lockName with a lock of owner and reports whether it did
+// when the process that holds it is not running, or when it has no process id and is
+// older than a minute as its writer crashed before writing it. The stale lock is never
+// removed, so no other writer can create a lock meanwhile, and writers taking it over
+// at the same time are serialized by a take-over file. The lock is read again while
+// holding that file and only replaced when it is still the stale one.
+func takeOverStaleLock(lockName string, owner string) bool {
+	info, err := os.Stat(lockName)
+	if err != nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(lockName)
+	if err != nil || !lockIsStale(data, info.ModTime()) {
+		return false
+	}
+	takeOverName := lockName + takeOverSuffix
+	f, err := os.OpenFile(takeOverName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		// A take-over only lasts a moment, so an old take-over file was left by a crash.
+		if info, statErr := os.Stat(takeOverName); statErr == nil && time.Since(info.ModTime()) > time.Minute {
+			os.Remove(takeOverName)
+		}
+		return false
+	}
+	f.Close()
+	defer os.Remove(takeOverName)
+	if current, err := ioutil.ReadFile(lockName); err != nil || string(current) != string(data) {
+		return false
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(lockName), filepath.Base(lockName)+".*.tmp")
+	if err != nil {
+		return false
+	}
+	_, err = tmp.WriteString(owner)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), lockName)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return false
+	}
+	return true
+}
+
+func lockIsStale(data []byte, modTime time.Time) bool {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return time.Since(modTime) > time.Minute
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return time.Since(modTime) > time.Minute
+	}
+	return !processRunning(pid)
+}
+
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess only finds running processes there.
+		process.Release()
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
+
+// writeJournal saves the bytes of f from offset on before an append overwrites them.
+// The journal starts with a line holding offset and the size of f.
+func writeJournal(f *os.File, fileName string, offset int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err = f.ReadAt(tail, offset); err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	journal, err := os.Create(fileName + journalSuffix)
+	if err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	fmt.Fprintf(journal, "%d,%d\n", offset, info.Size())
+	_, err = journal.Write(tail)
+	if err == nil {
+		err = journal.Sync()
+	}
+	if closeErr := journal.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fileName + journalSuffix)
+		return errors.New("File Write Error: " + err.Error())
+	}
+	syncDir(filepath.Dir(fileName))
+	return nil
+}
+
+// recoverAppend restores fileName from the journal of an interrupted append. A journal
+// that was not completely written is removed, as the file was not changed yet.
+func recoverAppend(fileName string) error {
+	journal, err := ioutil.ReadFile(fileName + journalSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.New("File Read Error: " + err.Error())
+	}
+	offset, size, tail, complete := parseJournal(journal)
+	if complete {
+		f, err := os.OpenFile(fileName, os.O_RDWR, 0)
+		if err != nil {
+			return errors.New("File Write Error: " + err.Error())
+		}
+		err = f.Truncate(offset)
+		if err == nil {
+			_, err = f.WriteAt(tail, offset)
+		}
+		if err == nil {
+			err = f.Truncate(size)
+		}
+		if err == nil {
+			err = f.Sync()
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return errors.New("File Write Error: " + err.Error())
+		}
+	}
+	return os.Remove(fileName + journalSuffix)
+}
+
+func parseJournal(journal []byte) (int64, int64, []byte, bool) {
+	newLine := strings.IndexByte(string(journal), '\n')
+	if newLine < 0 {
+		return 0, 0, nil, false
+	}
+	values := strings.Split(string(journal[:newLine]), ",")
+	if len(values) != 2 {
+		return 0, 0, nil, false
+	}
+	offset, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0, 0, nil, false
+	}
+	size, err := strconv.ParseInt(values[1], 10, 64)
+	tail := journal[newLine+1:]
+	return offset, size, tail, err == nil && int64(len(tail)) == size-offset
+}
+
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
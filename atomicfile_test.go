@@ -0,0 +1,160 @@
+package marketdata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getAtomicFileTestData() TickerData {
+	var td TickerData
+	td.Id = []int32{0, 1, 2}
+	td.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017"}, "1/2/2006")
+	td.Close = []float64{1, 2, 3}
+	return td
+}
+
+func TestAtomicFileLeavesOldFileOnFailure(t *testing.T) {
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	td := getAtomicFileTestData()
+	err := csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily"})
+	fwr, _ := csvWriter.createFile("testticker-daily.csv")
+	fwr.WriteString("id,date,close\n0,1/3/")
+	fwr.Close()
+	result, _ := ioutil.ReadFile(outputPath + "testticker-daily.csv")
+	files, _ := ioutil.ReadDir(outputPath)
+	expectedValue := "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n"
+	if err != nil || string(result) != expectedValue || len(files) != 1 {
+		t.Log("TestAtomicFileLeavesOldFileOnFailure failed. Result was: ", string(result), " with ", len(files), " files but should be: ", expectedValue, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestAtomicFileLocking(t *testing.T) {
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	td := getAtomicFileTestData()
+	lockName := outputPath + "testticker-daily.csv" + lockSuffix
+	ioutil.WriteFile(lockName, []byte("1\n"), 0644)
+	for _, appendMode := range []bool{false, true} {
+		err := csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily", Append: appendMode})
+		if appendMode {
+			ioutil.WriteFile(outputPath+"testticker-daily.csv", []byte("id,date,close\n"), 0644)
+			err = csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily", Append: appendMode})
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), "File Lock Error") {
+			t.Log("TestAtomicFileLocking should fail while the file is locked. Append: ", appendMode, " Error was: ", err)
+			t.Fail()
+		}
+	}
+	csvWriter.LockTimeout = time.Second
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		os.Remove(lockName)
+	}()
+	err := csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily"})
+	if _, statErr := os.Stat(lockName); err != nil || !os.IsNotExist(statErr) {
+		t.Log("TestAtomicFileLocking failed to wait for the lock. Error: ", err, statErr)
+		t.Fail()
+	}
+}
+
+func TestAtomicFileRemovesStaleLock(t *testing.T) {
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	td := getAtomicFileTestData()
+	lockName := outputPath + "testticker-daily.csv" + lockSuffix
+	// No process has this id, as process ids are far smaller.
+	ioutil.WriteFile(lockName, []byte(fmt.Sprintf("%d\n", 1<<30)), 0644)
+	err := csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily"})
+	files, _ := ioutil.ReadDir(outputPath)
+	if err != nil || len(files) != 1 {
+		t.Log("TestAtomicFileRemovesStaleLock failed to remove the lock of a crashed writer. Files: ", len(files), " Error: ", err)
+		t.Fail()
+	}
+	ioutil.WriteFile(lockName, []byte{}, 0644)
+	if err = csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily"}); err == nil {
+		t.Log("TestAtomicFileRemovesStaleLock should not remove a recent lock without a process id.")
+		t.Fail()
+	}
+}
+
+func TestAtomicFileTakesOverStaleLockOnce(t *testing.T) {
+	lockName := t.TempDir() + string(os.PathSeparator) + "testticker-daily.csv" + lockSuffix
+	stale := fmt.Sprintf("%d\n", 1<<30)
+	ioutil.WriteFile(lockName, []byte(stale), 0644)
+	ioutil.WriteFile(lockName+takeOverSuffix, []byte{}, 0644)
+	if takeOverStaleLock(lockName, "first\n") {
+		t.Log("TestAtomicFileTakesOverStaleLockOnce should not take over a lock while another writer takes it over.")
+		t.Fail()
+	}
+	os.Remove(lockName + takeOverSuffix)
+	taken := takeOverStaleLock(lockName, "first\n")
+	data, _ := ioutil.ReadFile(lockName)
+	if _, err := os.Stat(lockName + takeOverSuffix); !taken || string(data) != "first\n" || !os.IsNotExist(err) {
+		t.Log("TestAtomicFileTakesOverStaleLockOnce failed to take over the stale lock. Lock was: ", string(data))
+		t.Fail()
+	}
+	ioutil.WriteFile(lockName, []byte(fmt.Sprintf("%d 1\n", os.Getpid())), 0644)
+	if takeOverStaleLock(lockName, "second\n") {
+		t.Log("TestAtomicFileTakesOverStaleLockOnce should not take over the lock of a running writer.")
+		t.Fail()
+	}
+}
+
+func TestAtomicFileAppendFailsForUnopenableFile(t *testing.T) {
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	td := getAtomicFileTestData()
+	os.Mkdir(outputPath+"testticker-daily.csv", 0755)
+	err := csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily", Append: true})
+	if err == nil || !strings.HasPrefix(err.Error(), "File Open Error") {
+		t.Log("TestAtomicFileAppendFailsForUnopenableFile should fail to open the stored file. Error was: ", err)
+		t.Fail()
+	}
+}
+
+func TestAtomicFileRecoversInterruptedAppend(t *testing.T) {
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	fileName := outputPath + "testticker-daily.csv"
+	stored := "id,date,close,bar_count,incomplete\n0,1/3/2017,1,1,false\n1,1/4/2017,2,1,true\n"
+	ioutil.WriteFile(fileName, []byte(stored), 0644)
+	// Simulate a crash after the incomplete row was cut and a new row partly written.
+	f, _ := os.OpenFile(fileName, os.O_RDWR, 0)
+	offset := int64(strings.Index(stored, "1,1/4/2017"))
+	writeJournal(f, fileName, offset)
+	f.Truncate(offset)
+	f.WriteAt([]byte("1,1/4/20"), offset)
+	f.Close()
+	csvReader := CsvReader{DataPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	_, err := csvReader.readTickerData("testticker", &ReadConfig{TimeFrame: "daily"})
+	if err == nil || !strings.HasPrefix(err.Error(), "Incomplete Write Error") {
+		t.Log("TestAtomicFileRecoversInterruptedAppend should not read a file with an interrupted append. Error was: ", err)
+		t.Fail()
+	}
+	td := getAtomicFileTestData()
+	td.BarCount = []int32{1, 1, 1}
+	td.Incomplete = []bool{false, false, false}
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	err = csvWriter.writeTickerData("testticker", &td, &WriteConfig{TimeFrame: "daily", Append: true})
+	result, _ := ioutil.ReadFile(fileName)
+	files, _ := ioutil.ReadDir(outputPath)
+	expectedValue := "id,date,close,bar_count,incomplete\n0,1/3/2017,1,1,false\n1,1/4/2017,2,1,false\n2,1/5/2017,3,1,false\n"
+	if err != nil || string(result) != expectedValue || len(files) != 1 {
+		t.Log("TestAtomicFileRecoversInterruptedAppend failed. Result was: ", string(result), " with ", len(files), " files but should be: ", expectedValue, " Error: ", err)
+		t.Fail()
+	}
+	ioutil.WriteFile(fileName+journalSuffix, []byte("12,"), 0644)
+	if err = recoverAppend(fileName); err != nil {
+		t.Log("TestAtomicFileRecoversInterruptedAppend failed to drop an incomplete journal. Error: ", err)
+		t.Fail()
+	}
+	if result, _ = ioutil.ReadFile(fileName); string(result) != expectedValue {
+		t.Log("TestAtomicFileRecoversInterruptedAppend should not change the file for an incomplete journal. Result was: ", string(result))
+		t.Fail()
+	}
+}
@@ -0,0 +1,279 @@
+package marketdata
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachingReader wraps a DataReader and keeps the ticker data it reads in memory, so
+// repeated reads of a symbol, time frame, filter and date range are served without
+// reading and parsing the source again. At most MaxItems results are kept, dropping
+// the least recently used one first; 0 means no limit.
+//
+// When the wrapped reader identifies the content of its sources, like CsvReader, a
+// cached result is only used while the size and hash of its source are unchanged, and
+// when CacheDir is set the results are also persisted there to be reused by later runs
+// of a reader with the same settings. A source is only hashed again once its size or
+// modification time changed since the CachingReader last hashed it. Event, split, dividend, tick and quote data are
+// not cached. CachingReader is safe for concurrent use.
+type CachingReader struct {
+	Reader   DataReader
+	MaxItems int
+	CacheDir string
+	mutex    sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+	inFlight map[string]*cachedRead
+	versions map[string]stampedVersion
+}
+
+type stampedVersion struct {
+	stamp   string
+	version string
+}
+
+// versionedReader is implemented by readers whose results can be checked against
+// their source and persisted.
+// sourceStamp is cheap to get and changes whenever the source may have changed, while
+// sourceVersion identifies the content of the source.
+type versionedReader interface {
+	locatedReader
+	sourceStamp(kind string, name string, timeFrame string) (string, error)
+	sourceVersion(kind string, name string, timeFrame string) (string, error)
+	cacheIdentity() string
+}
+
+// locatedReader is implemented by readers that parse dates in a location.
+type locatedReader interface {
+	getLocation() *time.Location
+}
+
+type cachedTickerData struct {
+	Key     string
+	Version string
+	Data    TickerData
+}
+
+type cachedRead struct {
+	done sync.WaitGroup
+	td   TickerData
+	err  error
+}
+
+func NewCachingReader(reader DataReader, maxItems int, cacheDir string) *CachingReader {
+	return &CachingReader{Reader: reader, MaxItems: maxItems, CacheDir: cacheDir}
+}
+
+// Len returns the number of results held in memory.
+func (cr *CachingReader) Len() int {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	if cr.order == nil {
+		return 0
+	}
+	return cr.order.Len()
+}
+
+func (cr *CachingReader) readTickerData(symbol string, tickerConfig *ReadConfig) (TickerData, error) {
+	key := getTickerDataCacheKey(symbol, tickerConfig)
+	if vr, ok := cr.Reader.(versionedReader); ok {
+		key = vr.cacheIdentity() + "|" + key
+	}
+	version, versioned := cr.version(symbol, tickerConfig.TimeFrame)
+	cr.mutex.Lock()
+	if cr.items == nil {
+		cr.items = make(map[string]*list.Element)
+		cr.order = list.New()
+		cr.inFlight = make(map[string]*cachedRead)
+	}
+	if element, exists := cr.items[key]; exists {
+		item := element.Value.(*cachedTickerData)
+		if !versioned || item.Version == version {
+			cr.order.MoveToFront(element)
+			cr.mutex.Unlock()
+			return copyTickerDataRange(&item.Data, 0, len(item.Data.Date)), nil
+		}
+		cr.order.Remove(element)
+		delete(cr.items, key)
+	}
+	// Goroutines asking for the same data while it is read wait for that read.
+	if read, exists := cr.inFlight[key]; exists {
+		cr.mutex.Unlock()
+		read.done.Wait()
+		if read.err != nil {
+			return TickerData{}, read.err
+		}
+		return copyTickerDataRange(&read.td, 0, len(read.td.Date)), nil
+	}
+	read := &cachedRead{}
+	read.done.Add(1)
+	cr.inFlight[key] = read
+	cr.mutex.Unlock()
+
+	read.td, read.err = cr.readThrough(key, symbol, tickerConfig, version, versioned)
+	cr.mutex.Lock()
+	delete(cr.inFlight, key)
+	if read.err == nil {
+		cr.add(&cachedTickerData{key, version, read.td})
+	}
+	cr.mutex.Unlock()
+	read.done.Done()
+	if read.err != nil {
+		return TickerData{}, read.err
+	}
+	return copyTickerDataRange(&read.td, 0, len(read.td.Date)), nil
+}
+
+func (cr *CachingReader) readThrough(key string, symbol string, tickerConfig *ReadConfig, version string, versioned bool) (TickerData, error) {
+	persist := versioned && cr.CacheDir != ""
+	if persist {
+		if item, err := cr.load(key); err == nil && item.Key == key && item.Version == version {
+			// Gob keeps the offset of dates but not their location.
+			if loc := cr.Reader.(versionedReader).getLocation(); loc != nil {
+				item.Data.InLocation(loc)
+			}
+			return item.Data, nil
+		}
+	}
+	td, err := cr.Reader.readTickerData(symbol, tickerConfig)
+	if err != nil {
+		return td, err
+	}
+	if persist {
+		// The disk cache only saves work, so failing to write it does not fail the read.
+		cr.save(&cachedTickerData{key, version, td})
+	}
+	return td, nil
+}
+
+// add stores item as the most recently used one. The caller holds the mutex.
+func (cr *CachingReader) add(item *cachedTickerData) {
+	if element, exists := cr.items[item.Key]; exists {
+		cr.order.Remove(element)
+	}
+	cr.items[item.Key] = cr.order.PushFront(item)
+	for cr.MaxItems > 0 && cr.order.Len() > cr.MaxItems {
+		oldest := cr.order.Back()
+		cr.order.Remove(oldest)
+		delete(cr.items, oldest.Value.(*cachedTickerData).Key)
+	}
+}
+
+// version returns the version of the source of symbol. The source is only hashed again
+// when its stamp changed since the last read.
+func (cr *CachingReader) version(symbol string, timeFrame string) (string, bool) {
+	vr, ok := cr.Reader.(versionedReader)
+	if !ok {
+		return "", false
+	}
+	stamp, err := vr.sourceStamp("ticker", symbol, timeFrame)
+	if err != nil {
+		return "", false
+	}
+	key := vr.cacheIdentity() + "|" + symbol + "|" + timeFrame
+	cr.mutex.Lock()
+	known, exists := cr.versions[key]
+	cr.mutex.Unlock()
+	if exists && known.stamp == stamp {
+		return known.version, true
+	}
+	version, err := vr.sourceVersion("ticker", symbol, timeFrame)
+	if err != nil {
+		return "", false
+	}
+	cr.mutex.Lock()
+	if cr.versions == nil {
+		cr.versions = make(map[string]stampedVersion)
+	}
+	cr.versions[key] = stampedVersion{stamp, version}
+	cr.mutex.Unlock()
+	return version, true
+}
+
+func (cr *CachingReader) load(key string) (*cachedTickerData, error) {
+	f, err := os.Open(cr.cacheFileName(key))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var item cachedTickerData
+	err = gob.NewDecoder(f).Decode(&item)
+	return &item, err
+}
+
+// save replaces the cache file of item atomically, so processes sharing CacheDir never
+// read a partly written file. It fails while another process writes the same file.
+func (cr *CachingReader) save(item *cachedTickerData) error {
+	if err := os.MkdirAll(cr.CacheDir, 0755); err != nil {
+		return errors.New("Cache Error: " + err.Error())
+	}
+	af, err := createAtomicFile(cr.cacheFileName(item.Key), 0)
+	if err != nil {
+		return errors.New("Cache Error: " + err.Error())
+	}
+	defer af.Close()
+	if err = gob.NewEncoder(af).Encode(item); err != nil {
+		return errors.New("Cache Error: " + err.Error())
+	}
+	if err = af.commit(); err != nil {
+		return errors.New("Cache Error: " + err.Error())
+	}
+	return nil
+}
+
+func (cr *CachingReader) cacheFileName(key string) string {
+	hash := sha1.Sum([]byte(key))
+	return filepath.Join(cr.CacheDir, hex.EncodeToString(hash[:])+".gob")
+}
+
+func getTickerDataCacheKey(symbol string, tickerConfig *ReadConfig) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", symbol, tickerConfig.TimeFrame, strings.Join(tickerConfig.Filter, ","),
+		tickerConfig.Range.StartDate.Format(time.RFC3339Nano), tickerConfig.Range.EndDate.Format(time.RFC3339Nano))
+}
+
+func (cr *CachingReader) readEventData(event *Event) (EventData, error) {
+	return cr.Reader.readEventData(event)
+}
+
+func (cr *CachingReader) readDividendData(symbol string, source DataSource) (TickerDividendData, error) {
+	return cr.Reader.readDividendData(symbol, source)
+}
+
+func (cr *CachingReader) readSplitData(symbol string, source DataSource) (TickerSplitData, error) {
+	return cr.Reader.readSplitData(symbol, source)
+}
+
+func (cr *CachingReader) readTickData(symbol string, tickConfig *ReadConfig) (TickData, error) {
+	return cr.Reader.readTickData(symbol, tickConfig)
+}
+
+func (cr *CachingReader) readQuoteData(symbol string, quoteConfig *ReadConfig) (QuoteData, error) {
+	return cr.Reader.readQuoteData(symbol, quoteConfig)
+}
+
+func (cr *CachingReader) getDateFormat() string {
+	return cr.Reader.getDateFormat()
+}
+
+func (cr *CachingReader) getLocation() *time.Location {
+	if lr, ok := cr.Reader.(locatedReader); ok {
+		return lr.getLocation()
+	}
+	return nil
+}
+
+func (cr *CachingReader) modTime(kind string, name string, timeFrame string) (time.Time, error) {
+	if mtr, ok := cr.Reader.(modTimeReader); ok {
+		return mtr.modTime(kind, name, timeFrame)
+	}
+	return time.Time{}, errors.New("Modification time unknown.")
+}
@@ -0,0 +1,161 @@
+package marketdata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingReader struct {
+	CsvReader
+	reads    *int32
+	versions *int32
+}
+
+func (cr countingReader) sourceVersion(kind string, name string, timeFrame string) (string, error) {
+	atomic.AddInt32(cr.versions, 1)
+	return cr.CsvReader.sourceVersion(kind, name, timeFrame)
+}
+
+func (cr countingReader) readTickerData(symbol string, tickerConfig *ReadConfig) (TickerData, error) {
+	atomic.AddInt32(cr.reads, 1)
+	return cr.CsvReader.readTickerData(symbol, tickerConfig)
+}
+
+func getCountingReader(dataPath string) countingReader {
+	return countingReader{CsvReader{DataPath: dataPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}, new(int32), new(int32)}
+}
+
+func TestCachingReader(t *testing.T) {
+	reader := getCountingReader("." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker")
+	cr := NewCachingReader(reader, 1, "")
+	config := ReadConfig{TimeFrame: "daily"}
+	first, err := cr.readTickerData("someticker", &config)
+	first.Close[0] = 0
+	second, _ := cr.readTickerData("someticker", &config)
+	if err != nil || *reader.reads != 1 || second.Close[0] != 135.89 {
+		t.Log("TestCachingReader failed to serve a copy from memory. Reads were: ", *reader.reads, " Close was: ", second.Close[0], " Error: ", err)
+		t.Fail()
+	}
+	cr.readTickerData("someticker", &ReadConfig{TimeFrame: "daily", Range: DateRange{StartDate: second.Date[1]}})
+	cr.readTickerData("someticker", &config)
+	if *reader.reads != 3 || cr.Len() != 1 {
+		t.Log("TestCachingReader failed to drop the least recently used data. Reads were: ", *reader.reads, " but should be: 2")
+		t.Fail()
+	}
+	if _, err = cr.readTickerData("missing", &config); err == nil {
+		t.Log("TestCachingReader should return the error of the reader.")
+		t.Fail()
+	}
+	if *reader.versions != 1 {
+		t.Log("TestCachingReader should only hash an unchanged file once. Hashes were: ", *reader.versions)
+		t.Fail()
+	}
+}
+
+func TestCachingReaderInvalidatesChangedFiles(t *testing.T) {
+	dataPath := t.TempDir()
+	source, _ := ioutil.ReadFile(filepath.Join("testdata", "ticker", "someticker-daily.csv"))
+	fileName := filepath.Join(dataPath, "someticker-daily.csv")
+	ioutil.WriteFile(fileName, source, 0644)
+	reader := getCountingReader(dataPath)
+	cacheDir := t.TempDir()
+	cr := NewCachingReader(reader, 0, cacheDir)
+	config := ReadConfig{TimeFrame: "daily"}
+	expected, _ := cr.readTickerData("someticker", &config)
+	result, err := NewCachingReader(reader, 0, cacheDir).readTickerData("someticker", &config)
+	if err != nil || *reader.reads != 1 || !reflect.DeepEqual(result.Close, expected.Close) || !result.Date[0].Equal(expected.Date[0]) {
+		t.Log("TestCachingReader failed to reuse the disk cache. Reads were: ", *reader.reads, " but should be: 1 Error: ", err)
+		t.Fail()
+	}
+	// The changed file keeps its size and modification time, so only a reader that did
+	// not hash it yet notices the change.
+	info, _ := os.Stat(fileName)
+	ioutil.WriteFile(fileName, []byte(strings.Replace(string(source), "135.89", "135.88", 1)), 0644)
+	os.Chtimes(fileName, info.ModTime(), info.ModTime())
+	result, _ = NewCachingReader(reader, 0, cacheDir).readTickerData("someticker", &config)
+	if *reader.reads != 2 || result.Close[0] != 135.88 {
+		t.Log("TestCachingReader failed to invalidate a changed file. Reads were: ", *reader.reads, " but should be: 2")
+		t.Fail()
+	}
+	ioutil.WriteFile(fileName, []byte(strings.Replace(string(source), "135.89", "135.87", 1)), 0644)
+	os.Chtimes(fileName, info.ModTime(), info.ModTime().Add(time.Second))
+	result, _ = cr.readTickerData("someticker", &config)
+	if *reader.reads != 3 || result.Close[0] != 135.87 {
+		t.Log("TestCachingReader failed to invalidate a file held in memory. Reads were: ", *reader.reads, " but should be: 3")
+		t.Fail()
+	}
+}
+
+func TestCachingReaderSeparatesReaders(t *testing.T) {
+	cacheDir := t.TempDir()
+	modTime := time.Now()
+	var readers []countingReader
+	for _, content := range []string{"id,date,close\n0,1/3/2017,2\n", "id,date,close\n0,1/3/2017,6\n"} {
+		dataPath := t.TempDir()
+		fileName := filepath.Join(dataPath, "someticker-daily.csv")
+		ioutil.WriteFile(fileName, []byte(content), 0644)
+		os.Chtimes(fileName, modTime, modTime)
+		readers = append(readers, getCountingReader(dataPath))
+	}
+	config := ReadConfig{TimeFrame: "daily"}
+	first, err := NewCachingReader(readers[0], 0, cacheDir).readTickerData("someticker", &config)
+	second, secondErr := NewCachingReader(readers[1], 0, cacheDir).readTickerData("someticker", &config)
+	if err != nil || secondErr != nil || first.Close[0] != 2 || second.Close[0] != 6 {
+		t.Log("TestCachingReaderSeparatesReaders failed. Close was: ", first.Close, second.Close, " but should be: 2 and 6 Error: ", err, secondErr)
+		t.Fail()
+	}
+}
+
+func TestCachingReaderRestoresLocation(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	reader := getCountingReader("." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker")
+	reader.Location = loc
+	cacheDir := t.TempDir()
+	config := ReadConfig{TimeFrame: "daily"}
+	expected, _ := NewCachingReader(reader, 0, cacheDir).readTickerData("someticker", &config)
+	result, err := NewCachingReader(reader, 0, cacheDir).readTickerData("someticker", &config)
+	if err != nil || *reader.reads != 1 || !reflect.DeepEqual(result.Date, expected.Date) || result.Date[0].Location() != loc {
+		t.Log("TestCachingReaderRestoresLocation failed. Reads were: ", *reader.reads, " Dates were: ", result.Date, " but should be: ", expected.Date, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestCachingReaderIgnoresCacheWriteErrors(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(cacheDir, []byte{}, 0644)
+	reader := getCountingReader("." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker")
+	result, err := NewCachingReader(reader, 0, cacheDir).readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	if err != nil || len(result.Date) != 3 {
+		t.Log("TestCachingReaderIgnoresCacheWriteErrors failed. Result was: ", result, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestCachingReaderConcurrentReads(t *testing.T) {
+	reader := getCountingReader("." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker")
+	cr := NewCachingReader(reader, 2, "")
+	var wg sync.WaitGroup
+	var failures int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := ReadTickerData(cr, &TickerForRead{Symbol: "someticker", Config: []ReadConfig{{TimeFrame: "daily"}}})
+			if err != nil || len(data["daily"].Date) != 3 {
+				atomic.AddInt32(&failures, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if failures != 0 || *reader.reads != 1 {
+		t.Log("TestCachingReaderConcurrentReads failed. Reads were: ", *reader.reads, " but should be: 1 Failures: ", failures)
+		t.Fail()
+	}
+}
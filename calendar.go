@@ -0,0 +1,161 @@
+package marketdata
+
+import "time"
+
+// Calendar defines how dates are grouped into weekly, monthly, quarterly and yearly
+// periods. The zero value uses Sunday-start weeks and calendar months and years.
+// With Retail445 the fiscal year is split into 4-4-5 week months starting on the
+// WeekStart day nearest to the first day of FiscalYearStartMonth.
+// A Continuous calendar is for instruments that trade around the clock: dates are
+// evaluated in UTC, every day is a trading day and a new day starts at DayRollHour
+// UTC, so a day is labelled with the date on which it starts.
+type Calendar struct {
+	WeekStart            time.Weekday
+	IsoWeeks             bool
+	FiscalYearStartMonth time.Month
+	Retail445            bool
+	Continuous           bool
+	DayRollHour          int
+}
+
+var defaultCalendar = &Calendar{}
+
+// NewContinuousCalendar returns a calendar for 24/7 markets where weeks end on
+// Sunday 23:59 UTC and days roll at rollHour UTC.
+func NewContinuousCalendar(rollHour int) *Calendar {
+	return &Calendar{WeekStart: time.Monday, Continuous: true, DayRollHour: rollHour}
+}
+
+func (td *TickerData) calendar() *Calendar {
+	if td.Calendar == nil {
+		return defaultCalendar
+	}
+	return td.Calendar
+}
+
+func (cal *Calendar) weekStart() time.Weekday {
+	if cal.IsoWeeks {
+		return time.Monday
+	}
+	return cal.WeekStart
+}
+
+func (cal *Calendar) fiscalYearStartMonth() time.Month {
+	if cal.FiscalYearStartMonth < time.January || cal.FiscalYearStartMonth > time.December {
+		return time.January
+	}
+	return cal.FiscalYearStartMonth
+}
+
+// periodKey returns a number that is equal for all dates in the same period of timeFrame,
+// evaluated in the location of the date. A continuous calendar evaluates dates in UTC
+// and only shifts days by DayRollHour, so weeks and longer periods end at midnight UTC.
+func (cal *Calendar) periodKey(date time.Time, timeFrame string) int64 {
+	if cal.Continuous && timeFrameRank(timeFrame) > 0 {
+		date = date.UTC()
+		if timeFrame == "daily" {
+			date = date.Add(-time.Duration(cal.DayRollHour) * time.Hour)
+		}
+	}
+	switch timeFrame {
+	case "daily":
+		return dayNumber(date)
+	case "weekly":
+		if cal.IsoWeeks {
+			year, week := date.ISOWeek()
+			return int64(year)*100 + int64(week)
+		}
+		return dayNumber(date) - int64((7+date.Weekday()-cal.weekStart())%7)
+	case "monthly":
+		if cal.Retail445 {
+			year, month := cal.retailPeriod(date)
+			return int64(year)*12 + int64(month)
+		}
+		return int64(date.Year())*12 + int64(date.Month())
+	case "quarterly":
+		year, month := cal.fiscalPeriod(date)
+		return int64(year)*4 + int64(month/3)
+	case "yearly":
+		year, _ := cal.fiscalPeriod(date)
+		return int64(year)
+	}
+	duration, _ := time.ParseDuration(timeFrame)
+	return date.Truncate(duration).Unix()
+}
+
+func (cal *Calendar) isLastDayOfTimeFrame(date time.Time, timeFrame string) bool {
+	next := date.AddDate(0, 0, 1)
+	if !cal.Continuous && next.Weekday() == time.Saturday {
+		next = next.AddDate(0, 0, 2)
+	}
+	return cal.periodKey(date, timeFrame) != cal.periodKey(next, timeFrame)
+}
+
+// fiscalPeriod returns the year in which the fiscal year of date starts and the
+// zero based month of date within that fiscal year.
+func (cal *Calendar) fiscalPeriod(date time.Time) (int, int) {
+	if cal.Retail445 {
+		return cal.retailPeriod(date)
+	}
+	startMonth := cal.fiscalYearStartMonth()
+	year := date.Year()
+	if date.Month() < startMonth {
+		year--
+	}
+	return year, (int(date.Month()) - int(startMonth) + 12) % 12
+}
+
+func (cal *Calendar) retailPeriod(date time.Time) (int, int) {
+	day := dayNumber(date)
+	year := date.Year()
+	start := cal.retailYearStart(year)
+	if day < start {
+		year--
+		start = cal.retailYearStart(year)
+	} else if next := cal.retailYearStart(year + 1); day >= next {
+		year++
+		start = next
+	}
+	week := int((day - start) / 7)
+	quarter := week / 13
+	if quarter > 3 {
+		quarter = 3
+	}
+	week = week - quarter*13
+	month := 2
+	if week < 4 {
+		month = 0
+	} else if week < 8 {
+		month = 1
+	}
+	return year, quarter*3 + month
+}
+
+func (cal *Calendar) retailYearStart(year int) int64 {
+	first := time.Date(year, cal.fiscalYearStartMonth(), 1, 0, 0, 0, 0, time.UTC)
+	offset := (7 + int(cal.weekStart()) - int(first.Weekday())) % 7
+	if offset > 3 {
+		offset = offset - 7
+	}
+	return dayNumber(first) + int64(offset)
+}
+
+func dayNumber(date time.Time) int64 {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+func timeFrameRank(timeFrame string) int {
+	switch timeFrame {
+	case "daily":
+		return 1
+	case "weekly":
+		return 2
+	case "monthly":
+		return 3
+	case "quarterly":
+		return 4
+	case "yearly":
+		return 5
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package marketdata
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddHigherTimeFrameIdsWithCalendar(t *testing.T) {
+	weekendDates := []string{"1/6/2017", "1/7/2017", "1/8/2017", "1/9/2017", "1/10/2017"}
+	fiscalDates := []string{"1/31/2017", "2/1/2017", "4/28/2017", "5/1/2017"}
+	retailDates := []string{"2/24/2017", "2/27/2017", "3/24/2017", "3/27/2017", "4/28/2017", "5/1/2017"}
+	testCases := []struct {
+		name        string
+		calendar    *Calendar
+		dates       []string
+		higherTf    string
+		expectedIds []int32
+	}{
+		{"'Default calendar starts weeks on Sunday'", nil, weekendDates, "weekly", []int32{-1, -1, 0, 0, 0}},
+		{"'Monday week start'", &Calendar{WeekStart: time.Monday}, weekendDates, "weekly", []int32{-1, -1, -1, 0, 0}},
+		{"'Saturday week start'", &Calendar{WeekStart: time.Saturday}, weekendDates, "weekly", []int32{-1, 0, 0, 0, 0}},
+		{"'ISO weeks'", &Calendar{IsoWeeks: true}, weekendDates, "weekly", []int32{-1, -1, -1, 0, 0}},
+		{"'Fiscal quarters starting in February'", &Calendar{FiscalYearStartMonth: time.February}, fiscalDates, "quarterly", []int32{-1, 0, 0, 1}},
+		{"'Fiscal years starting in February'", &Calendar{FiscalYearStartMonth: time.February}, fiscalDates, "yearly", []int32{-1, 0, 0, 0}},
+		{"'Calendar months'", nil, retailDates, "monthly", []int32{-1, -1, 0, 0, 1, 2}},
+		{"'4-4-5 retail months'", &Calendar{FiscalYearStartMonth: time.February, Retail445: true}, retailDates, "monthly", []int32{-1, 0, 0, 1, 1, 2}},
+		{"'4-4-5 retail quarters'", &Calendar{FiscalYearStartMonth: time.February, Retail445: true}, retailDates, "quarterly", []int32{-1, -1, -1, -1, -1, 0}},
+	}
+	for _, tc := range testCases {
+		var td TickerData
+		td.Date = createDates(tc.dates, "1/2/2006")
+		td.Calendar = tc.calendar
+		td.HigherTfIds = map[string][]int32{tc.higherTf + "_id": make([]int32, len(td.Date))}
+		td.addHigherTimeFrameIds("daily", tc.higherTf)
+		if !reflect.DeepEqual(td.HigherTfIds[tc.higherTf+"_id"], tc.expectedIds) {
+			t.Log("TestAddHigherTimeFrameIdsWithCalendar test case ", tc.name, " failed. Result was: ", td.HigherTfIds, " but should be: ", tc.expectedIds)
+			t.Fail()
+		}
+	}
+}
+
+func TestIsLastDayOfTimeFrame(t *testing.T) {
+	testCases := []struct {
+		name      string
+		calendar  *Calendar
+		date      string
+		timeFrame string
+		expected  bool
+	}{
+		{"'Friday ends the week'", defaultCalendar, "1/6/2017", "weekly", true},
+		{"'Thursday does not end the week'", defaultCalendar, "1/5/2017", "weekly", false},
+		{"'Friday before a month end on the weekend'", defaultCalendar, "3/29/2019", "monthly", true},
+		{"'Last trading day of a calendar quarter'", defaultCalendar, "3/31/2017", "quarterly", true},
+		{"'Last trading day of a fiscal year'", &Calendar{FiscalYearStartMonth: time.July}, "6/30/2017", "yearly", true},
+		{"'Calendar year end inside a fiscal year'", &Calendar{FiscalYearStartMonth: time.July}, "12/29/2017", "yearly", false},
+	}
+	for _, tc := range testCases {
+		date, _ := time.Parse("1/2/2006", tc.date)
+		if tc.calendar.isLastDayOfTimeFrame(date, tc.timeFrame) != tc.expected {
+			t.Log("TestIsLastDayOfTimeFrame test case ", tc.name, " failed. Result should be: ", tc.expected)
+			t.Fail()
+		}
+	}
+}
+
+func TestContinuousCalendar(t *testing.T) {
+	cal := NewContinuousCalendar(22)
+	var td TickerData
+	td.Date = createDates([]string{"2017-01-06T22:00:00Z", "2017-01-07T22:00:00Z", "2017-01-08T22:00:00Z", "2017-01-09T22:00:00Z"}, time.RFC3339)
+	td.Calendar = cal
+	td.HigherTfIds = map[string][]int32{"weekly_id": make([]int32, len(td.Date))}
+	td.addHigherTimeFrameIds("daily", "weekly")
+	expectedIds := []int32{-1, -1, -1, 0}
+	if !reflect.DeepEqual(td.HigherTfIds["weekly_id"], expectedIds) {
+		t.Log("TestContinuousCalendar failed to add weekly ids. Result was: ", td.HigherTfIds["weekly_id"], " but should be: ", expectedIds)
+		t.Fail()
+	}
+	ticks := createDates([]string{"2017-01-03T21:59:00Z", "2017-01-03T22:00:00Z", "2017-01-04T01:00:00Z"}, time.RFC3339)
+	if cal.periodKey(ticks[0], "daily") == cal.periodKey(ticks[1], "daily") || cal.periodKey(ticks[1], "daily") != cal.periodKey(ticks[2], "daily") {
+		t.Log("TestContinuousCalendar failed to roll the day at 22:00 UTC.")
+		t.Fail()
+	}
+	cal = NewContinuousCalendar(1)
+	ticks = createDates([]string{"2017-01-08T23:30:00Z", "2017-01-09T00:30:00Z"}, time.RFC3339)
+	if cal.periodKey(ticks[0], "daily") != cal.periodKey(ticks[1], "daily") || cal.periodKey(ticks[0], "weekly") == cal.periodKey(ticks[1], "weekly") {
+		t.Log("TestContinuousCalendar failed to end the week on Sunday 23:59 UTC with a roll hour of 1.")
+		t.Fail()
+	}
+	testCases := []struct {
+		date      string
+		timeFrame string
+		expected  bool
+	}{
+		{"1/6/2017", "weekly", false},
+		{"1/8/2017", "weekly", true},
+		{"3/29/2019", "monthly", false},
+		{"3/31/2019", "monthly", true},
+	}
+	cal = NewContinuousCalendar(0)
+	for _, tc := range testCases {
+		date, _ := time.Parse("1/2/2006", tc.date)
+		if cal.isLastDayOfTimeFrame(date, tc.timeFrame) != tc.expected {
+			t.Log("TestContinuousCalendar failed for ", tc.date, " ", tc.timeFrame, ". Result should be: ", tc.expected)
+			t.Fail()
+		}
+	}
+}
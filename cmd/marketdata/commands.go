@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fasterbull/marketdata"
+)
+
+func convert(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	cf := addCommonFlags(fs, true)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	td, err := cf.readTickerData()
+	if err != nil {
+		return err
+	}
+	csvWriter, err := cf.writer()
+	if err != nil {
+		return err
+	}
+	processedTd := process(td, *cf.timeFrame, []string{})
+	ticker := marketdata.TickerForWrite{Symbol: *cf.symbol, BaseTimeFrame: *cf.timeFrame, Config: []marketdata.WriteConfig{{TimeFrame: *cf.timeFrame}}}
+	if err = marketdata.WriteTickerData(csvWriter, &processedTd, &ticker); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote %d %s bars of %s.\n", len(processedTd.Date), *cf.timeFrame, *cf.symbol)
+	return nil
+}
+
+func resample(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("resample", flag.ContinueOnError)
+	cf := addCommonFlags(fs, true)
+	timeFrames := fs.String("timeframes", "weekly,monthly", "comma separated higher time frames to write")
+	includeIncomplete := fs.Bool("include-incomplete", false, "also write the incomplete last bar of each time frame")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	td, err := cf.readTickerData()
+	if err != nil {
+		return err
+	}
+	csvWriter, err := cf.writer()
+	if err != nil {
+		return err
+	}
+	higherTfs := strings.Split(*timeFrames, ",")
+	ticker := marketdata.TickerForWrite{Symbol: *cf.symbol, BaseTimeFrame: *cf.timeFrame}
+	for _, higherTf := range higherTfs {
+		ticker.Config = append(ticker.Config, marketdata.WriteConfig{TimeFrame: higherTf, IncludeIncomplete: *includeIncomplete})
+	}
+	processedTd := process(td, *cf.timeFrame, higherTfs)
+	if err = marketdata.WriteTickerData(csvWriter, &processedTd, &ticker); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote %s bars of %s.\n", strings.Join(higherTfs, ", "), *cf.symbol)
+	return nil
+}
+
+func adjust(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("adjust", flag.ContinueOnError)
+	cf := addCommonFlags(fs, true)
+	splitPattern := fs.String("split-pattern", "", "file name pattern of the split file")
+	dividendPattern := fs.String("dividend-pattern", "", "file name pattern of the dividend file")
+	yahooPattern := fs.String("yahoo-pattern", "", "file name pattern of a combined Yahoo split and dividend file")
+	actionDateFormat := fs.String("action-date-format", "20060102", "date format of the split and dividend files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *splitPattern == "" && *dividendPattern == "" && *yahooPattern == "" {
+		return errors.New("One of -split-pattern, -dividend-pattern or -yahoo-pattern is required.")
+	}
+	td, err := cf.readTickerData()
+	if err != nil {
+		return err
+	}
+	csvWriter, err := cf.writer()
+	if err != nil {
+		return err
+	}
+	csvReader, _ := cf.reader()
+	csvReader.DateFormat = *actionDateFormat
+	ca := marketdata.CorporateActions{Symbol: *cf.symbol}
+	if *yahooPattern != "" {
+		csvReader.FileNamePattern = *yahooPattern
+		yahooCa, err := marketdata.ReadCorporateActions(csvReader, *cf.symbol, marketdata.YAHOO)
+		if err != nil {
+			return err
+		}
+		ca.Merge(yahooCa.Actions)
+	}
+	if *splitPattern != "" {
+		csvReader.FileNamePattern = *splitPattern
+		tsd, err := marketdata.ReadSplitData(csvReader, *cf.symbol, marketdata.OTHER)
+		if err != nil {
+			return err
+		}
+		splitCa := marketdata.NewCorporateActions(*cf.symbol, &tsd, nil)
+		ca.Merge(splitCa.Actions)
+	}
+	if *dividendPattern != "" {
+		csvReader.FileNamePattern = *dividendPattern
+		tdd, err := marketdata.ReadDividendData(csvReader, *cf.symbol, marketdata.OTHER)
+		if err != nil {
+			return err
+		}
+		dividendCa := marketdata.NewCorporateActions(*cf.symbol, nil, &tdd)
+		ca.Merge(dividendCa.Actions)
+	}
+	processedTd := process(td, *cf.timeFrame, []string{})
+	processedTd.AdjustForCorporateActions(&ca)
+	ticker := marketdata.TickerForWrite{Symbol: *cf.symbol, BaseTimeFrame: *cf.timeFrame, Config: []marketdata.WriteConfig{{TimeFrame: *cf.timeFrame}}}
+	if err = marketdata.WriteTickerData(csvWriter, &processedTd, &ticker); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Adjusted %d bars of %s for %d corporate actions.\n", len(processedTd.Date), *cf.symbol, len(ca.Actions))
+	return nil
+}
+
+func validate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	cf := addCommonFlags(fs, false)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	td, err := cf.readTickerData()
+	if err != nil {
+		return err
+	}
+	// Files may be stored newest first, so only the order after sorting is validated.
+	processedTd := process(td, *cf.timeFrame, []string{})
+	issues := marketdata.ValidateTickerData(&processedTd)
+	for _, issue := range issues {
+		fmt.Fprintf(stdout, "%d\t%s\t%s\n", issue.Index, issue.Date.Format(*cf.dateFormat), issue.Message)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("Found %d invalid bars in %s.", len(issues), *cf.symbol)
+	}
+	fmt.Fprintf(stdout, "%s is valid.\n", *cf.symbol)
+	return nil
+}
+
+func info(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	cf := addCommonFlags(fs, false)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	td, err := cf.readTickerData()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Symbol:     %s\n", *cf.symbol)
+	fmt.Fprintf(stdout, "Time frame: %s\n", *cf.timeFrame)
+	fmt.Fprintf(stdout, "Bars:       %d\n", len(td.Date))
+	if len(td.Date) > 0 {
+		first, last := td.Date[0], td.Date[len(td.Date)-1]
+		if first.After(last) {
+			first, last = last, first
+		}
+		fmt.Fprintf(stdout, "First date: %s\n", first.Format(*cf.dateFormat))
+		fmt.Fprintf(stdout, "Last date:  %s\n", last.Format(*cf.dateFormat))
+	}
+	var higherTfIds []string
+	for key := range td.HigherTfIds {
+		higherTfIds = append(higherTfIds, key)
+	}
+	sort.Strings(higherTfIds)
+	if len(higherTfIds) > 0 {
+		fmt.Fprintf(stdout, "Linked ids: %s\n", strings.Join(higherTfIds, ", "))
+	}
+	return nil
+}
+
+func runPipeline(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	configFile := fs.String("config", "", "JSON pipeline configuration file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configFile == "" {
+		return errors.New("Flag -config is required.")
+	}
+	config, err := marketdata.LoadPipelineConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, status := range marketdata.RunPipeline(&config) {
+		if status.Err != nil {
+			failed++
+			fmt.Fprintf(stdout, "%s\tFAILED\t%v\n", status.Symbol, status.Err)
+		} else {
+			fmt.Fprintf(stdout, "%s\tOK\t%d bars\n", status.Symbol, status.Bars)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("Pipeline failed for %d of %d symbols.", failed, len(config.Symbols))
+	}
+	return nil
+}
+
+func serve(args []string, stdout io.Writer) error {
+	server, addr, err := newServer(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Serving on %s.\n", addr)
+	return http.ListenAndServe(addr, server)
+}
+
+func newServer(args []string) (*marketdata.Server, string, error) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	dataPath := fs.String("data-path", ".", "directory of the ticker, split and dividend files")
+	fileNamePattern := fs.String("pattern", "{ticker}-{timeframe}.csv", "file name pattern of the ticker files")
+	dateFormat := fs.String("date-format", "1/2/2006", "date format of the ticker and event files")
+	location := fs.String("location", "", "exchange location used to parse dates, e.g. America/New_York")
+	splitPattern := fs.String("split-pattern", "{ticker}-splitdata.csv", "file name pattern of the split files")
+	dividendPattern := fs.String("dividend-pattern", "{ticker}-dividenddata.csv", "file name pattern of the dividend files")
+	actionDateFormat := fs.String("action-date-format", "20060102", "date format of the split and dividend files")
+	source := fs.String("source", string(marketdata.OTHER), "format of the split and dividend files, OTHER or YAHOO")
+	eventPath := fs.String("event-path", "", "directory of the event files, events are not served when empty")
+	eventPattern := fs.String("event-pattern", "{eventname}.csv", "file name pattern of the event files")
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	var loc *time.Location
+	if *location != "" {
+		var err error
+		if loc, err = time.LoadLocation(*location); err != nil {
+			return nil, "", err
+		}
+	}
+	server := marketdata.Server{
+		TickerReader:   marketdata.CsvReader{DataPath: *dataPath, FileNamePattern: *fileNamePattern, DateFormat: *dateFormat, Location: loc},
+		SplitReader:    marketdata.CsvReader{DataPath: *dataPath, FileNamePattern: *splitPattern, DateFormat: *actionDateFormat, Location: loc},
+		DividendReader: marketdata.CsvReader{DataPath: *dataPath, FileNamePattern: *dividendPattern, DateFormat: *actionDateFormat, Location: loc},
+		Source:         marketdata.DataSource(*source),
+	}
+	if *eventPath != "" {
+		server.EventReader = marketdata.CsvReader{DataPath: *eventPath, FileNamePattern: *eventPattern, DateFormat: *dateFormat, Location: loc}
+	}
+	return &server, *addr, nil
+}
@@ -0,0 +1,163 @@
+// Command marketdata converts, resamples, adjusts, validates and describes ticker
+// data files using the CSV reader and writer of the marketdata package.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fasterbull/marketdata"
+)
+
+const usage = `Usage: marketdata <command> [flags]
+
+Commands:
+  convert    rewrite a ticker data file with another name pattern, date format or location
+  resample   write higher time frame files from daily data
+  adjust     adjust ticker data for split and dividend files
+  validate   report inconsistent bars
+  info       describe a ticker data file
+  run        run a JSON pipeline configuration file
+  serve      serve bars, splits, dividends and events over HTTP
+
+Run 'marketdata <command> -h' for the flags of a command.
+`
+
+type commonFlags struct {
+	symbol           *string
+	timeFrame        *string
+	dataPath         *string
+	fileNamePattern  *string
+	dateFormat       *string
+	location         *string
+	pricePrecision   *int
+	detectPrecision  *bool
+	outputPath       *string
+	outputPattern    *string
+	outputDateFormat *string
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, usage)
+		return errors.New("No command given.")
+	}
+	command := commands[args[0]]
+	if command == nil {
+		fmt.Fprint(stdout, usage)
+		return errors.New("Unknown command " + args[0] + ".")
+	}
+	return command(args[1:], stdout)
+}
+
+var commands map[string]func(args []string, stdout io.Writer) error
+
+func init() {
+	commands = map[string]func(args []string, stdout io.Writer) error{
+		"convert":  convert,
+		"resample": resample,
+		"adjust":   adjust,
+		"validate": validate,
+		"info":     info,
+		"run":      runPipeline,
+		"serve":    serve,
+	}
+}
+
+func addCommonFlags(fs *flag.FlagSet, withOutput bool) *commonFlags {
+	var cf commonFlags
+	cf.symbol = fs.String("symbol", "", "ticker symbol")
+	cf.timeFrame = fs.String("timeframe", "daily", "time frame of the input file")
+	cf.dataPath = fs.String("data-path", ".", "directory of the input files")
+	cf.fileNamePattern = fs.String("pattern", "{ticker}-{timeframe}.csv", "file name pattern of the input files")
+	cf.dateFormat = fs.String("date-format", "1/2/2006", "date format of the input files")
+	cf.location = fs.String("location", "", "exchange location used to parse and format dates, e.g. America/New_York")
+	cf.pricePrecision = fs.Int("precision", -1, "number of decimal places of prices, 2 when negative and not detected")
+	cf.detectPrecision = fs.Bool("detect-precision", false, "detect the number of decimal places of prices from the input file")
+	if withOutput {
+		cf.outputPath = fs.String("output-path", ".", "directory of the output files")
+		cf.outputPattern = fs.String("output-pattern", "", "file name pattern of the output files, defaults to -pattern")
+		cf.outputDateFormat = fs.String("output-date-format", "", "date format of the output files, defaults to -date-format")
+	}
+	return &cf
+}
+
+func (cf *commonFlags) loadLocation() (*time.Location, error) {
+	if *cf.location == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(*cf.location)
+}
+
+func (cf *commonFlags) reader() (marketdata.CsvReader, error) {
+	if *cf.symbol == "" {
+		return marketdata.CsvReader{}, errors.New("Flag -symbol is required.")
+	}
+	loc, err := cf.loadLocation()
+	csvReader := marketdata.CsvReader{DataPath: *cf.dataPath, FileNamePattern: *cf.fileNamePattern, DateFormat: *cf.dateFormat,
+		DetectPrecision: *cf.detectPrecision, Location: loc}
+	if *cf.pricePrecision >= 0 {
+		csvReader.PricePrecision = marketdata.NewPrecision(*cf.pricePrecision)
+	}
+	return csvReader, err
+}
+
+func (cf *commonFlags) writer() (marketdata.CsvWriter, error) {
+	loc, err := cf.loadLocation()
+	pattern := *cf.outputPattern
+	if pattern == "" {
+		pattern = *cf.fileNamePattern
+	}
+	dateFormat := *cf.outputDateFormat
+	if dateFormat == "" {
+		dateFormat = *cf.dateFormat
+	}
+	return marketdata.CsvWriter{OutputPath: *cf.outputPath + string(os.PathSeparator), FileNamePattern: pattern, DateFormat: dateFormat, Location: loc}, err
+}
+
+func (cf *commonFlags) readTickerData() (*marketdata.TickerData, error) {
+	csvReader, err := cf.reader()
+	if err != nil {
+		return nil, err
+	}
+	data, err := marketdata.ReadTickerData(csvReader, &marketdata.TickerForRead{Symbol: *cf.symbol, Config: []marketdata.ReadConfig{{TimeFrame: *cf.timeFrame}}})
+	if err != nil {
+		return nil, err
+	}
+	return data[*cf.timeFrame], nil
+}
+
+// process sorts td in ascending order and recomputes the ids of the linked higher time
+// frames already present in td as well as those of higherTfs.
+func process(td *marketdata.TickerData, timeFrame string, higherTfs []string) marketdata.TickerData {
+	for key := range td.HigherTfIds {
+		higherTf := strings.TrimSuffix(key, "_id")
+		found := false
+		for _, tf := range higherTfs {
+			found = found || tf == higherTf
+		}
+		if !found {
+			higherTfs = append(higherTfs, higherTf)
+		}
+	}
+	sort.Strings(higherTfs)
+	td.HigherTfIds = nil
+	addFields := []string{"id"}
+	for _, higherTf := range higherTfs {
+		addFields = append(addFields, higherTf+"_id")
+	}
+	return marketdata.ProcessRawTickerData(td, &marketdata.TickerSplitData{}, timeFrame, addFields, higherTfs)
+}
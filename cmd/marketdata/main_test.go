@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var dataPath = filepath.Join("..", "..", "testdata", "ticker")
+
+func TestRun(t *testing.T) {
+	outputPath := t.TempDir()
+	actionPath := t.TempDir()
+	for _, name := range []string{"someticker-daily.csv", "someticker-yahoosplitdividend.csv"} {
+		data, _ := ioutil.ReadFile(filepath.Join(dataPath, name))
+		ioutil.WriteFile(filepath.Join(actionPath, name), data, 0644)
+	}
+	ioutil.WriteFile(filepath.Join(actionPath, "someticker-splitdata.csv"), []byte("Date, Split\n20161208,2:1\n20050609,2:1\n"), 0644)
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+		expectedFile   string
+		expectedLine   string
+	}{
+		{"'Convert the date format'", []string{"convert", "-symbol", "spy", "-data-path", dataPath, "-date-format", "2006-01-02", "-output-path", outputPath, "-output-date-format", "1/2/2006"},
+			"Wrote 6030 daily bars of spy.", "spy-daily.csv", "6029,1/6/2017,226.529999,227.75,225.899994,227.210007,69536300"},
+		{"'Resample to weekly bars'", []string{"resample", "-symbol", "someticker", "-data-path", dataPath, "-timeframes", "weekly", "-include-incomplete", "-output-path", outputPath},
+			"Wrote weekly bars of someticker.", "someticker-weekly.csv", "0,-1,12/7/2016,134.58,138.82,134.17,138.3,112930300,3,false"},
+		{"'Adjust for splits'", []string{"adjust", "-symbol", "someticker", "-data-path", actionPath, "-split-pattern", "{ticker}-splitdata.csv", "-output-path", outputPath, "-output-pattern", "{ticker}-adjusted.csv"},
+			"Adjusted 3 bars of someticker for 2 corporate actions.", "someticker-adjusted.csv", "0,-1,-1,12/7/2016,67.29,68.08,67.08,67.94,61718600"},
+		{"'Adjust for Yahoo and split files'", []string{"adjust", "-symbol", "someticker", "-data-path", actionPath, "-yahoo-pattern", "{ticker}-yahoosplitdividend.csv", "-split-pattern", "{ticker}-splitdata.csv", "-output-path", outputPath, "-output-pattern", "{ticker}-merged.csv"},
+			"Adjusted 3 bars of someticker for 7 corporate actions.", "someticker-merged.csv", "0,-1,-1,12/7/2016,67.29,68.08,67.08,67.94,61718600"},
+		{"'Validate'", []string{"validate", "-symbol", "someticker", "-data-path", dataPath}, "someticker is valid.", "", ""},
+		{"'Validate data stored newest first'", []string{"validate", "-symbol", "spy", "-data-path", dataPath, "-date-format", "2006-01-02"}, "spy is valid.", "", ""},
+		{"'Info'", []string{"info", "-symbol", "someticker", "-data-path", dataPath}, "Linked ids: monthly_id, weekly_id", "", ""},
+	}
+	for _, tc := range testCases {
+		var stdout bytes.Buffer
+		err := run(tc.args, &stdout)
+		if err != nil || !strings.Contains(stdout.String(), tc.expectedOutput) {
+			t.Log("TestRun test case ", tc.name, " failed. Output was: ", stdout.String(), " but should contain: ", tc.expectedOutput, " Error: ", err)
+			t.Fail()
+			continue
+		}
+		if tc.expectedFile == "" {
+			continue
+		}
+		result, _ := ioutil.ReadFile(filepath.Join(outputPath, tc.expectedFile))
+		if !strings.Contains(string(result), tc.expectedLine+"\n") {
+			t.Log("TestRun test case ", tc.name, " failed to write ", tc.expectedFile, ". Result was: ", string(result), " but should contain: ", tc.expectedLine)
+			t.Fail()
+		}
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	outputPath := t.TempDir()
+	configFile := filepath.Join(outputPath, "pipeline.json")
+	config := `{
+		"source": {"data_path": "` + filepath.ToSlash(dataPath) + `", "file_name_pattern": "{ticker}-{timeframe}.csv", "date_format": "1/2/2006"},
+		"output": {"output_path": "` + filepath.ToSlash(outputPath) + `/", "file_name_pattern": "{ticker}-{timeframe}.csv", "date_format": "1/2/2006"},
+		"symbols": ["someticker"],
+		"base_timeframe": "daily",
+		"timeframes": ["daily", "weekly"]
+	}`
+	ioutil.WriteFile(configFile, []byte(config), 0644)
+	var stdout bytes.Buffer
+	err := run([]string{"run", "-config", configFile}, &stdout)
+	_, statErr := os.Stat(filepath.Join(outputPath, "someticker-weekly.csv"))
+	if err != nil || stdout.String() != "someticker\tOK\t3 bars\n" || statErr != nil {
+		t.Log("TestRunPipeline failed. Output was: ", stdout.String(), " Error: ", err, statErr)
+		t.Fail()
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	server, addr, err := newServer([]string{"-addr", ":9000", "-data-path", dataPath})
+	if err != nil || addr != ":9000" {
+		t.Log("TestNewServer failed. Result was: ", addr, " Error: ", err)
+		t.FailNow()
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/splits/someticker", nil))
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Body.String(), `[{"date":"2002-06-05"`) {
+		t.Log("TestNewServer failed to serve splits. Result was: ", recorder.Code, " ", recorder.Body.String())
+		t.Fail()
+	}
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/events/testevent", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Log("TestNewServer should not serve events without an event path. Result was: ", recorder.Code)
+		t.Fail()
+	}
+	if _, _, err = newServer([]string{"-location", "Nowhere/Nothing"}); err == nil {
+		t.Log("TestNewServer should fail for an unknown location.")
+		t.Fail()
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	testCases := [][]string{
+		{},
+		{"unknown"},
+		{"info"},
+		{"info", "-symbol", "missing", "-data-path", dataPath},
+		{"adjust", "-symbol", "someticker", "-data-path", dataPath},
+		{"adjust", "-symbol", "someticker", "-data-path", dataPath, "-split-pattern", "{ticker}-splitdata.csv", "-action-date-format", "1/2/2006", "-output-path", os.TempDir()},
+		{"run"},
+		{"run", "-config", "missing.json"},
+	}
+	for _, args := range testCases {
+		var stdout bytes.Buffer
+		if err := run(args, &stdout); err == nil {
+			t.Log("TestRunFailures should fail for arguments: ", args)
+			t.Fail()
+		}
+	}
+}
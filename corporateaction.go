@@ -0,0 +1,362 @@
+package marketdata
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type CorporateActionType string
+
+const (
+	SPLIT            CorporateActionType = "SPLIT"
+	DIVIDEND         CorporateActionType = "DIVIDEND"
+	SPECIAL_DIVIDEND CorporateActionType = "SPECIAL_DIVIDEND"
+	SPIN_OFF         CorporateActionType = "SPIN_OFF"
+	RIGHTS_ISSUE     CorporateActionType = "RIGHTS_ISSUE"
+	MERGER           CorporateActionType = "MERGER"
+	SYMBOL_CHANGE    CorporateActionType = "SYMBOL_CHANGE"
+)
+
+// CorporateAction describes a single event on its ex-date. BeforeQty and AfterQty
+// hold share ratios (splits, rights issues and stock mergers), Amount holds the
+// cash or distributed value per share (dividends, spin-offs) or the subscription
+// price of a rights issue, and OldSymbol/NewSymbol describe renames and mergers.
+type CorporateAction struct {
+	Date      time.Time
+	Type      CorporateActionType
+	BeforeQty int
+	AfterQty  int
+	Amount    float64
+	OldSymbol string
+	NewSymbol string
+}
+
+type CorporateActions struct {
+	Symbol  string
+	Actions []CorporateAction
+}
+
+func NewCorporateActions(symbol string, tsd *TickerSplitData, tdd *TickerDividendData) CorporateActions {
+	ca := CorporateActions{Symbol: symbol}
+	if tsd != nil {
+		for i := range tsd.Date {
+			ca.Actions = append(ca.Actions, CorporateAction{Date: tsd.Date[i], Type: SPLIT, BeforeQty: tsd.BeforeSplitQty[i], AfterQty: tsd.AfterSplitQty[i]})
+		}
+	}
+	if tdd != nil {
+		for i := range tdd.Date {
+			ca.Actions = append(ca.Actions, CorporateAction{Date: tdd.Date[i], Type: DIVIDEND, Amount: tdd.Amount[i]})
+		}
+	}
+	ca.sort()
+	return ca
+}
+
+func ReadCorporateActions(dataReader DataReader, symbol string, source DataSource) (CorporateActions, error) {
+	var ca CorporateActions
+	tsd, err := ReadSplitData(dataReader, symbol, source)
+	if err != nil {
+		return ca, err
+	}
+	tdd, err := ReadDividendData(dataReader, symbol, source)
+	if err != nil {
+		return ca, err
+	}
+	return NewCorporateActions(symbol, &tsd, &tdd), nil
+}
+
+func (ca *CorporateActions) Add(action CorporateAction) {
+	ca.Actions = append(ca.Actions, action)
+	ca.sort()
+}
+
+// Merge adds the actions that ca does not have yet, so an action found in several
+// sources, like a Yahoo file and a split file, is applied once.
+func (ca *CorporateActions) Merge(actions []CorporateAction) {
+	for _, action := range actions {
+		exists := false
+		for _, existing := range ca.Actions {
+			if existing.Type == action.Type && existing.Date.Equal(action.Date) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			ca.Add(action)
+		}
+	}
+}
+
+func (ca *CorporateActions) SplitData() TickerSplitData {
+	var tsd TickerSplitData
+	for _, action := range ca.Actions {
+		if action.Type == SPLIT {
+			tsd.Date = append(tsd.Date, action.Date)
+			tsd.BeforeSplitQty = append(tsd.BeforeSplitQty, action.BeforeQty)
+			tsd.AfterSplitQty = append(tsd.AfterSplitQty, action.AfterQty)
+		}
+	}
+	return tsd
+}
+
+func (ca *CorporateActions) DividendData() TickerDividendData {
+	var tdd TickerDividendData
+	for _, action := range ca.Actions {
+		if action.Type == DIVIDEND || action.Type == SPECIAL_DIVIDEND {
+			tdd.Date = append(tdd.Date, action.Date)
+			tdd.Amount = append(tdd.Amount, action.Amount)
+		}
+	}
+	return tdd
+}
+
+type AdjustmentFactorSeries struct {
+	Date         []time.Time
+	PriceFactor  []float64
+	VolumeFactor []float64
+}
+
+// AdjustmentFactors returns, for every bar in td, the cumulative factors that convert
+// the raw traded price and volume of that bar into values comparable with the last bar.
+// Actions after the last bar do not change any bar.
+func (ca *CorporateActions) AdjustmentFactors(td *TickerData) ([]float64, []float64) {
+	return ca.adjustmentFactors(td, time.Time{})
+}
+
+// AdjustmentFactorsAsOf is like AdjustmentFactors but ignores every action that was
+// not yet known on asOf, so that no later split or dividend leaks into the result.
+func (ca *CorporateActions) AdjustmentFactorsAsOf(td *TickerData, asOf time.Time) ([]float64, []float64) {
+	return ca.adjustmentFactors(td, asOf)
+}
+
+func (ca *CorporateActions) AdjustmentFactorSeries(td *TickerData) AdjustmentFactorSeries {
+	var afs AdjustmentFactorSeries
+	afs.Date = td.Date
+	afs.PriceFactor, afs.VolumeFactor = ca.AdjustmentFactors(td)
+	return afs
+}
+
+// PriceFactorAsOf returns the factor that converts the raw price of the bar at index
+// into the adjusted price displayed on the date of the bar at asOfIndex.
+func (afs *AdjustmentFactorSeries) PriceFactorAsOf(index int, asOfIndex int) float64 {
+	if index >= asOfIndex {
+		return 1
+	}
+	return afs.PriceFactor[index] / afs.PriceFactor[asOfIndex]
+}
+
+func (afs *AdjustmentFactorSeries) VolumeFactorAsOf(index int, asOfIndex int) float64 {
+	if index >= asOfIndex {
+		return 1
+	}
+	return afs.VolumeFactor[index] / afs.VolumeFactor[asOfIndex]
+}
+
+func (td *TickerData) AdjustedAsOf(ca *CorporateActions, asOf time.Time) TickerData {
+	end := 0
+	for end < len(td.Date) && !td.Date[end].After(asOf) {
+		end++
+	}
+	asOfTd := copyTickerDataRange(td, 0, end)
+	priceFactors, volumeFactors := ca.AdjustmentFactorsAsOf(&asOfTd, asOf)
+	dp := asOfTd.pricePrecision()
+	for i := 0; i < end; i++ {
+		if priceFactors[i] != 1 || volumeFactors[i] != 1 {
+			asOfTd.adjustTickerDataItem(i, priceFactors[i], volumeFactors[i], dp)
+		}
+	}
+	return asOfTd
+}
+
+func (ca *CorporateActions) adjustmentFactors(td *TickerData, asOf time.Time) ([]float64, []float64) {
+	l := len(td.Date)
+	priceFactors := make([]float64, l)
+	volumeFactors := make([]float64, l)
+	priceFactor := 1.0
+	volumeFactor := 1.0
+	a := ca.lastActionIndex(td)
+	for i := l - 1; i > -1; i-- {
+		for ; a > -1 && ca.Actions[a].Date.After(td.Date[i]); a-- {
+			if !asOf.IsZero() && ca.Actions[a].Date.After(asOf) {
+				continue
+			}
+			p, v := ca.Actions[a].factors(td, i)
+			priceFactor = priceFactor * p
+			volumeFactor = volumeFactor * v
+		}
+		priceFactors[i] = priceFactor
+		volumeFactors[i] = volumeFactor
+	}
+	return priceFactors, volumeFactors
+}
+
+// unadjustmentFactors derives the factors from already adjusted data. Dividend and
+// rights issue factors depend on the raw close before the ex-date, which is solved
+// for by fixed-point iteration on the adjusted close.
+func (ca *CorporateActions) unadjustmentFactors(td *TickerData) ([]float64, []float64) {
+	l := len(td.Date)
+	priceFactors := make([]float64, l)
+	volumeFactors := make([]float64, l)
+	priceFactor := 1.0
+	volumeFactor := 1.0
+	a := ca.lastActionIndex(td)
+	var rawTd TickerData
+	rawTd.Close = make([]float64, l)
+	for i := l - 1; i > -1; i-- {
+		first := a
+		for a > -1 && ca.Actions[a].Date.After(td.Date[i]) {
+			a--
+		}
+		if first != a {
+			barPriceFactor := 1.0
+			barVolumeFactor := 1.0
+			for iteration := 0; iteration < 100; iteration++ {
+				rawTd.Close[i] = td.Close[i] / (priceFactor * barPriceFactor)
+				p, v := 1.0, 1.0
+				for x := first; x > a; x-- {
+					actionPriceFactor, actionVolumeFactor := ca.Actions[x].factors(&rawTd, i)
+					p = p * actionPriceFactor
+					v = v * actionVolumeFactor
+				}
+				converged := math.Abs(p-barPriceFactor) < 1e-15
+				barPriceFactor = p
+				barVolumeFactor = v
+				if converged {
+					break
+				}
+			}
+			priceFactor = priceFactor * barPriceFactor
+			volumeFactor = volumeFactor * barVolumeFactor
+		}
+		priceFactors[i] = priceFactor
+		volumeFactors[i] = volumeFactor
+	}
+	return priceFactors, volumeFactors
+}
+
+// lastActionIndex returns the index of the last action on or before the last bar of td.
+func (ca *CorporateActions) lastActionIndex(td *TickerData) int {
+	a := len(ca.Actions) - 1
+	if l := len(td.Date); l > 0 {
+		for a > -1 && ca.Actions[a].Date.After(td.Date[l-1]) {
+			a--
+		}
+	}
+	return a
+}
+
+func (td *TickerData) AdjustForCorporateActions(ca *CorporateActions) {
+	priceFactors, volumeFactors := ca.AdjustmentFactors(td)
+	dp := td.pricePrecision()
+	l := len(td.Date)
+	for i := 0; i < l; i++ {
+		if priceFactors[i] != 1 || volumeFactors[i] != 1 {
+			td.adjustTickerDataItem(i, priceFactors[i], volumeFactors[i], dp)
+		}
+	}
+}
+
+func (action *CorporateAction) factors(td *TickerData, prevIndex int) (float64, float64) {
+	switch action.Type {
+	case SPLIT, MERGER:
+		if action.BeforeQty <= 0 || action.AfterQty <= 0 {
+			return 1, 1
+		}
+		return float64(action.BeforeQty) / float64(action.AfterQty), float64(action.AfterQty) / float64(action.BeforeQty)
+	case DIVIDEND, SPECIAL_DIVIDEND, SPIN_OFF:
+		prevClose := td.Close[prevIndex]
+		if prevClose <= 0 || action.Amount >= prevClose {
+			return 1, 1
+		}
+		return 1 - action.Amount/prevClose, 1
+	case RIGHTS_ISSUE:
+		prevClose := td.Close[prevIndex]
+		if prevClose <= 0 || action.BeforeQty <= 0 || action.AfterQty <= action.BeforeQty {
+			return 1, 1
+		}
+		newQty := float64(action.AfterQty - action.BeforeQty)
+		terp := (float64(action.BeforeQty)*prevClose + newQty*action.Amount) / float64(action.AfterQty)
+		return terp / prevClose, 1
+	}
+	return 1, 1
+}
+
+func (ca *CorporateActions) symbolChanges() []CorporateAction {
+	changes := []CorporateAction{}
+	for _, action := range ca.Actions {
+		if (action.Type == SYMBOL_CHANGE || action.Type == MERGER) && action.OldSymbol != "" {
+			changes = append(changes, action)
+		}
+	}
+	return changes
+}
+
+func (ca *CorporateActions) sort() {
+	sort.SliceStable(ca.Actions, func(i, j int) bool { return ca.Actions[i].Date.Before(ca.Actions[j].Date) })
+}
+
+func stitchRenamedTickerData(dataReader DataReader, td *TickerData, ca *CorporateActions, tickerConfig *ReadConfig) (TickerData, error) {
+	stitchedTd := *td
+	changes := ca.symbolChanges()
+	for x := len(changes) - 1; x > -1; x-- {
+		cutOffDate := changes[x].Date
+		if len(stitchedTd.Date) > 0 && stitchedTd.Date[0].Before(cutOffDate) {
+			cutOffDate = stitchedTd.Date[0]
+		}
+		oldTd, err := dataReader.readTickerData(changes[x].OldSymbol, tickerConfig)
+		if err != nil {
+			return stitchedTd, errors.New("Unable to read data for previous symbol '" + changes[x].OldSymbol + "': " + err.Error())
+		}
+		stitchedTd = joinTickerData(&oldTd, &stitchedTd, cutOffDate)
+	}
+	// The linked ids of the joined pieces count from the start of each piece.
+	if len(changes) > 0 {
+		for key := range stitchedTd.HigherTfIds {
+			stitchedTd.addHigherTimeFrameIds(tickerConfig.TimeFrame, strings.TrimSuffix(key, "_id"))
+		}
+	}
+	return stitchedTd, nil
+}
+
+func joinTickerData(earlierTd *TickerData, laterTd *TickerData, cutOffDate time.Time) TickerData {
+	var td TickerData
+	fields := getFields(laterTd, []string{}, "")
+	earlierSize := 0
+	for earlierSize < len(earlierTd.Date) && earlierTd.Date[earlierSize].Before(cutOffDate) {
+		earlierSize++
+	}
+	td.initialize(fields, earlierSize+len(laterTd.Date))
+	td.Precision = laterTd.Precision
+	td.Calendar = laterTd.Calendar
+	index := 0
+	for i := 0; i < earlierSize; i++ {
+		td.addItem(earlierTd, index, i, index)
+		index++
+	}
+	for i := range laterTd.Date {
+		td.addItem(laterTd, index, i, index)
+		index++
+	}
+	return td
+}
+
+func copyTickerDataRange(inTd *TickerData, begin int, end int) TickerData {
+	var td TickerData
+	td.initialize(getFields(inTd, []string{}, ""), end-begin)
+	td.Precision = inTd.Precision
+	td.Calendar = inTd.Calendar
+	for i := begin; i < end; i++ {
+		id := i - begin
+		if inTd.Id != nil {
+			id = int(inTd.Id[i])
+		}
+		td.addItem(inTd, id, i, i-begin)
+		for key := range inTd.HigherTfIds {
+			td.HigherTfIds[key][i-begin] = inTd.HigherTfIds[key][i]
+		}
+	}
+	return td
+}
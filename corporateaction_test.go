@@ -0,0 +1,230 @@
+package marketdata
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAdjustForCorporateActions(t *testing.T) {
+	tsd := getTickerSplitData()
+	ca := NewCorporateActions("testticker", &tsd, nil)
+	processedTd, _ := getTestPreSplitAdjustedTickerData("asc", 0)
+	processedTd.AdjustForCorporateActions(&ca)
+	expectedResult := getTestSplitAdjustedTickerData()
+	// A single cumulative pass rounds once, so the first close is not double rounded.
+	expectedResult.Close[0] = 75.42
+	if !reflect.DeepEqual(processedTd, expectedResult) {
+		t.Log("TestAdjustForCorporateActions failed to adjust ticker data for splits. Result was: ", processedTd, " but should be: ", expectedResult)
+		t.Fail()
+	}
+}
+
+func TestCorporateActionAdjustmentFactors(t *testing.T) {
+	var td TickerData
+	td.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017", "1/6/2017"}, "1/2/2006")
+	td.Close = []float64{100, 50, 40, 40}
+	ca := CorporateActions{Symbol: "testticker"}
+	ca.Add(CorporateAction{Date: td.Date[3], Type: DIVIDEND, Amount: 2})
+	ca.Add(CorporateAction{Date: td.Date[1], Type: SPLIT, BeforeQty: 1, AfterQty: 2})
+	ca.Add(CorporateAction{Date: td.Date[2], Type: SYMBOL_CHANGE, OldSymbol: "oldticker", NewSymbol: "testticker"})
+	// An action after the last bar does not change any bar.
+	ca.Add(CorporateAction{Date: td.Date[3].AddDate(0, 0, 3), Type: SPLIT, BeforeQty: 1, AfterQty: 4})
+	priceFactors, volumeFactors := ca.AdjustmentFactors(&td)
+	expectedPriceFactors := []float64{0.475, 0.95, 0.95, 1}
+	expectedVolumeFactors := []float64{2, 1, 1, 1}
+	if !reflect.DeepEqual(priceFactors, expectedPriceFactors) || !reflect.DeepEqual(volumeFactors, expectedVolumeFactors) {
+		t.Log("TestCorporateActionAdjustmentFactors failed. Result was: ", priceFactors, volumeFactors, " but should be: ", expectedPriceFactors, expectedVolumeFactors)
+		t.Fail()
+	}
+}
+
+func TestReadCorporateActions(t *testing.T) {
+	var csvReader CsvReader
+	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	csvReader.FileNamePattern = "{ticker}-yahoosplitdividend.csv"
+	csvReader.DateFormat = "20060102"
+	result, err := ReadCorporateActions(csvReader, "someticker", YAHOO)
+	expectedTypes := []CorporateActionType{DIVIDEND, DIVIDEND, SPLIT, DIVIDEND, SPLIT, DIVIDEND}
+	resultTypes := []CorporateActionType{}
+	for _, action := range result.Actions {
+		resultTypes = append(resultTypes, action.Type)
+	}
+	tsd := result.SplitData()
+	expectedTsd, _ := ReadSplitData(csvReader, "someticker", YAHOO)
+	if err != nil || !reflect.DeepEqual(resultTypes, expectedTypes) || !reflect.DeepEqual(tsd, expectedTsd) {
+		t.Log("TestReadCorporateActions failed. Result was: ", result, " but should have types: ", expectedTypes, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestReadTickerDataStitchesRenamedSymbol(t *testing.T) {
+	var csvReader CsvReader
+	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	csvReader.FileNamePattern = "{ticker}-{timeframe}.csv"
+	csvReader.DateFormat = "1/2/2006"
+	ca := CorporateActions{Symbol: "newname"}
+	changeDate, _ := time.Parse(csvReader.DateFormat, "12/7/2016")
+	ca.Add(CorporateAction{Date: changeDate, Type: SYMBOL_CHANGE, OldSymbol: "oldname", NewSymbol: "newname"})
+	tickerForRead := TickerForRead{Symbol: "newname", Config: []ReadConfig{{TimeFrame: "daily"}}, CorporateActions: &ca}
+	result, err := ReadTickerData(csvReader, &tickerForRead)
+	expectedDates := createDates([]string{"12/5/2016", "12/6/2016", "12/7/2016", "12/8/2016", "12/9/2016"}, csvReader.DateFormat)
+	expectedClose := []float64{10.20, 10.40, 10.65, 10.80, 10.90}
+	if err != nil || !reflect.DeepEqual(result["daily"].Date, expectedDates) || !reflect.DeepEqual(result["daily"].Close, expectedClose) {
+		t.Log("TestReadTickerDataStitchesRenamedSymbol failed. Result was: ", result["daily"], " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestReadTickerDataStitchesLinkedIds(t *testing.T) {
+	dataPath := t.TempDir()
+	files := map[string]string{
+		"oldname-weekly.csv": "id,monthly_id,date,close\n0,-1,11/21/2016,1\n1,-1,11/28/2016,2\n2,0,12/5/2016,3\n",
+		"newname-weekly.csv": "id,monthly_id,date,close\n0,-1,12/12/2016,4\n1,-1,12/19/2016,5\n2,-1,12/26/2016,6\n3,0,1/2/2017,7\n",
+		"oldname-1h.csv":     "id,daily_id,date,close\n0,-1,12/8/2016 15:00,1\n1,0,12/9/2016 9:00,2\n",
+		"newname-1h.csv":     "id,daily_id,date,close\n0,-1,12/9/2016 10:00,3\n1,0,12/12/2016 9:00,4\n",
+	}
+	for name, content := range files {
+		ioutil.WriteFile(dataPath+string(os.PathSeparator)+name, []byte(content), 0644)
+	}
+	ca := CorporateActions{Symbol: "newname"}
+	changeDate := createDates([]string{"12/9/2016"}, "1/2/2006")[0]
+	ca.Add(CorporateAction{Date: changeDate, Type: SYMBOL_CHANGE, OldSymbol: "oldname", NewSymbol: "newname"})
+	testCases := []struct {
+		timeFrame     string
+		dateFormat    string
+		idField       string
+		expectedValue []int32
+	}{
+		{"weekly", "1/2/2006", "monthly_id", []int32{-1, -1, 0, 0, 0, 0, 1}},
+		{"1h", "1/2/2006 15:04", "daily_id", []int32{-1, 0, 1}},
+	}
+	for _, tc := range testCases {
+		csvReader := CsvReader{DataPath: dataPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: tc.dateFormat}
+		result, err := ReadTickerData(csvReader, &TickerForRead{Symbol: "newname", Config: []ReadConfig{{TimeFrame: tc.timeFrame}}, CorporateActions: &ca})
+		if err != nil || !reflect.DeepEqual(result[tc.timeFrame].HigherTfIds[tc.idField], tc.expectedValue) {
+			t.Log("TestReadTickerDataStitchesLinkedIds failed for ", tc.timeFrame, ". Result was: ", result[tc.timeFrame], " but ", tc.idField, " should be: ", tc.expectedValue, " Error: ", err)
+			t.Fail()
+		}
+	}
+}
+
+func TestAdjustedAsOf(t *testing.T) {
+	tsd := getTickerSplitData()
+	ca := NewCorporateActions("testticker", &tsd, nil)
+	rawTd, _ := getTestPreSplitAdjustedTickerData("asc", 0)
+	asOf, _ := time.Parse("1/2/2006", "12/30/2016")
+	result := rawTd.AdjustedAsOf(&ca, asOf)
+	var expectedResult TickerData
+	expectedResult.Date = createDates([]string{"12/28/2016", "12/29/2016", "12/30/2016"}, "1/2/2006")
+	expectedResult.Open = []float64{113.01, 113.01, 113.01}
+	expectedResult.High = []float64{113.37, 113.37, 113.37}
+	expectedResult.Low = []float64{113, 113, 113}
+	expectedResult.Close = []float64{113.14, 113.14, 113.14}
+	expectedResult.Volume = []int64{82108800, 82108800, 82108800}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Log("TestAdjustedAsOf failed. Result was: ", result, " but should be: ", expectedResult)
+		t.Fail()
+	}
+	afs := ca.AdjustmentFactorSeries(&rawTd)
+	if afs.PriceFactorAsOf(0, 2) != 0.5 || afs.PriceFactorAsOf(0, 3) != 1.0/3 || afs.VolumeFactorAsOf(1, 3) != 1.5 || afs.PriceFactorAsOf(3, 1) != 1 {
+		t.Log("TestAdjustedAsOf failed to convert factors. Series was: ", afs)
+		t.Fail()
+	}
+}
+
+func TestUnadjust(t *testing.T) {
+	var rawTd TickerData
+	rawTd.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017", "1/6/2017", "1/9/2017"}, "1/2/2006")
+	rawTd.Open = []float64{99.87, 101.13, 50.42, 49.99, 47.56}
+	rawTd.High = []float64{101.01, 102.22, 51.03, 50.17, 48.01}
+	rawTd.Low = []float64{99.5, 100.87, 49.88, 49.03, 47.12}
+	rawTd.Close = []float64{100.33, 101.97, 50.01, 49.07, 47.81}
+	// Volumes above 2^24 are not exact as float32.
+	rawTd.Volume = []int64{123456789, 1100, 2400, 2300, 2200}
+	ca := CorporateActions{Symbol: "testticker"}
+	ca.Add(CorporateAction{Date: rawTd.Date[2], Type: SPLIT, BeforeQty: 2, AfterQty: 3})
+	ca.Add(CorporateAction{Date: rawTd.Date[4], Type: DIVIDEND, Amount: 1.13})
+	testCases := []struct {
+		name          string
+		storeFactors  bool
+		actionHistory *CorporateActions
+	}{
+		{"'Unadjust using the stored factors and the corporate action history'", true, &ca},
+		{"'Unadjust using the stored factors'", true, nil},
+		{"'Unadjust using the corporate action history'", false, &ca},
+	}
+	for _, tc := range testCases {
+		td := copyTickerDataRange(&rawTd, 0, len(rawTd.Date))
+		if tc.storeFactors {
+			td.AdjFactor = []float64{1, 1, 1, 1, 1}
+			td.VolumeFactor = []float64{1, 1, 1, 1, 1}
+		}
+		td.AdjustForCorporateActions(&ca)
+		td.Unadjust(tc.actionHistory)
+		if !reflect.DeepEqual(td.Volume, rawTd.Volume) {
+			t.Log("TestUnadjust test case ", tc.name, " failed to restore raw volumes. Result was: ", td.Volume, " but should be: ", rawTd.Volume)
+			t.Fail()
+		}
+		if tc.storeFactors {
+			expectedFactors := []float64{1, 1, 1, 1, 1}
+			if !reflect.DeepEqual(td.AdjFactor, expectedFactors) || !reflect.DeepEqual(td.VolumeFactor, expectedFactors) {
+				t.Log("TestUnadjust test case ", tc.name, " failed to reset the stored factors. Result was: ", td.AdjFactor, td.VolumeFactor)
+				t.Fail()
+			}
+		}
+		if !restoredPricesMatch(&td, &rawTd, 0.01) {
+			t.Log("TestUnadjust test case ", tc.name, " failed to restore raw prices. Result was: ", td, " but should be: ", rawTd)
+			t.Fail()
+		}
+	}
+}
+
+func TestAdjFactorRoundTripsThroughCsv(t *testing.T) {
+	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	csvReader := CsvReader{DataPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	tsd := getTickerSplitData()
+	rawTd, _ := getTestPreSplitAdjustedTickerData("asc", 0)
+	// Volumes above 2^24 are not exact as float32.
+	rawTd.Volume[0] = 41054401
+	var emptyTsd TickerSplitData
+	td := ProcessRawTickerData(&rawTd, &emptyTsd, "daily", []string{"id", "adj_factor"}, []string{})
+	td.AdjustTickerDataForSplits(&tsd)
+	err := csvWriter.writeTickerData("adjfactorticker", &td, &WriteConfig{TimeFrame: "daily", Append: false})
+	readTd, readErr := csvReader.readTickerData("adjfactorticker", &ReadConfig{TimeFrame: "daily"})
+	os.Remove(outputPath + "adjfactorticker-daily.csv")
+	readTd.Unadjust(nil)
+	ones := []float64{1, 1, 1, 1}
+	if !reflect.DeepEqual(readTd.AdjFactor, ones) || !reflect.DeepEqual(readTd.VolumeFactor, ones) {
+		t.Log("TestAdjFactorRoundTripsThroughCsv failed to reset the stored factors. Result was: ", readTd.AdjFactor, readTd.VolumeFactor)
+		t.Fail()
+	}
+	// The splits leave a factor of 1/3 on the first bar, so its prices can be off by two cents.
+	if err != nil || readErr != nil || !reflect.DeepEqual(readTd.Date, rawTd.Date) || !reflect.DeepEqual(readTd.Volume, rawTd.Volume) || !restoredPricesMatch(&readTd, &rawTd, 0.02) {
+		t.Log("TestAdjFactorRoundTripsThroughCsv failed. Result was: ", readTd, " but should be: ", rawTd, " Errors: ", err, readErr)
+		t.Fail()
+	}
+}
+
+// restoredPricesMatch reports whether the prices of td are within tolerance of the ones
+// of rawTd. Adjusted prices are rounded to cents, so unadjusting them is off by up to
+// half a cent divided by the adjustment factor.
+func restoredPricesMatch(td *TickerData, rawTd *TickerData, tolerance float64) bool {
+	prices := [][]float64{td.Open, td.High, td.Low, td.Close}
+	rawPrices := [][]float64{rawTd.Open, rawTd.High, rawTd.Low, rawTd.Close}
+	for p := range prices {
+		if len(prices[p]) != len(rawPrices[p]) {
+			return false
+		}
+		for i := range prices[p] {
+			if math.Abs(prices[p][i]-rawPrices[p][i]) > tolerance+1e-9 {
+				return false
+			}
+		}
+	}
+	return true
+}
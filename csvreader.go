@@ -2,43 +2,160 @@ package marketdata
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha1"
 	"encoding/csv"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
+// CsvReader reads ticker data files from DataPath. The price precision of the ticker
+// data read is taken from SymbolPrecision, then PricePrecision, and otherwise detected
+// from the file when DetectPrecision is set. Without any of them prices have 2 decimals.
 type CsvReader struct {
 	DataPath        string
 	FileNamePattern string
 	DateFormat      string
+	PricePrecision  *int
+	SymbolPrecision map[string]int
+	DetectPrecision bool
+	Location        *time.Location
 }
 
+const maxReadAttempts = 3
+
 type indexRange struct {
 	begin int
 	end   int
 }
 
 func (csvReader CsvReader) readTickerData(symbol string, tickerConfig *ReadConfig) (TickerData, error) {
-	var tickerData TickerData
-	fileName := getTickerDataFileName(csvReader.FileNamePattern, symbol, tickerConfig.TimeFrame)
-	filePath := csvReader.DataPath + string(os.PathSeparator) + fileName
-	f, err := os.Open(filePath)
+	body, err := csvReader.readFile(getTickerDataFileName(csvReader.FileNamePattern, symbol, tickerConfig.TimeFrame))
+	if err != nil {
+		return TickerData{}, err
+	}
+	tickerData, err := parseTickerData(bytes.NewReader(body), tickerConfig, csvReader.DateFormat, csvReader.Location)
+	if err != nil {
+		return tickerData, err
+	}
+	tickerData.Precision = resolvePricePrecision(&tickerData, symbol, csvReader.PricePrecision, csvReader.SymbolPrecision, csvReader.DetectPrecision)
+	return tickerData, nil
+}
+
+func (csvReader CsvReader) readEventData(event *Event) (EventData, error) {
+	body, err := csvReader.readFile(getEventDataFileName(csvReader.FileNamePattern, event.Name))
+	if err != nil {
+		return EventData{Date: make(map[time.Time]bool)}, err
+	}
+	return parseEventData(bytes.NewReader(body), csvReader.DateFormat, csvReader.Location)
+}
+
+func (csvReader CsvReader) readDividendData(symbol string, source DataSource) (TickerDividendData, error) {
+	body, err := csvReader.readFile(getFileName(csvReader.FileNamePattern, "{ticker}", symbol))
+	if err != nil {
+		return TickerDividendData{}, err
+	}
+	return parseDividendData(bytes.NewReader(body), source, csvReader.DateFormat, csvReader.Location)
+}
+
+func (csvReader CsvReader) readSplitData(symbol string, source DataSource) (TickerSplitData, error) {
+	fileName := getFileName(csvReader.FileNamePattern, "{ticker}", symbol)
+	if fileName == "" {
+		return TickerSplitData{}, errors.New("File for ticker: '" + symbol + "' does not exist.")
+	}
+	body, err := csvReader.readFile(fileName)
+	if err != nil {
+		return TickerSplitData{}, err
+	}
+	return parseSplitData(bytes.NewReader(body), source, csvReader.DateFormat, csvReader.Location)
+}
+
+func (csvReader CsvReader) readTickData(symbol string, tickConfig *ReadConfig) (TickData, error) {
+	var tickData TickData
+	err := csvReader.readColumnarData(&tickData, symbol, tickConfig, []string{"price", "size"})
+	tickData.Precision = resolvePricePrecision(&TickerData{Close: tickData.Price}, symbol, csvReader.PricePrecision, csvReader.SymbolPrecision, csvReader.DetectPrecision)
+	return tickData, err
+}
+
+func (csvReader CsvReader) readQuoteData(symbol string, quoteConfig *ReadConfig) (QuoteData, error) {
+	var quoteData QuoteData
+	err := csvReader.readColumnarData(&quoteData, symbol, quoteConfig, []string{"bid", "ask"})
+	return quoteData, err
+}
+
+func (csvReader CsvReader) readColumnarData(data Data, symbol string, config *ReadConfig, requiredFields []string) error {
+	body, err := csvReader.readFile(getTickerDataFileName(csvReader.FileNamePattern, symbol, config.TimeFrame))
 	if err != nil {
-		return tickerData, errors.New("File Open Error: " + err.Error())
+		return err
+	}
+	return parseColumnarData(data, bytes.NewReader(body), config, requiredFields, csvReader.DateFormat, csvReader.Location)
+}
+
+// notFoundError is the error of reading a file that does not exist, which Server
+// reports as 404.
+type notFoundError struct {
+	message string
+}
+
+func (e notFoundError) Error() string {
+	return e.message
+}
+
+// readFile reads a whole file. An append only changes a file while its journal exists,
+// so the file is read between two checks for the journal and read again when its size
+// or modification time changed meanwhile.
+func (csvReader CsvReader) readFile(fileName string) ([]byte, error) {
+	filePath := csvReader.DataPath + string(os.PathSeparator) + fileName
+	for attempt := 0; ; attempt++ {
+		if _, err := os.Stat(filePath + journalSuffix); err == nil {
+			return nil, errors.New("Incomplete Write Error: " + filePath + " is being appended to or an append was interrupted. It is restored by the next append.")
+		}
+		before, err := os.Stat(filePath)
+		if os.IsNotExist(err) {
+			return nil, notFoundError{"File Open Error: " + err.Error()}
+		}
+		if err != nil {
+			return nil, errors.New("File Open Error: " + err.Error())
+		}
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, errors.New("File Open Error: " + err.Error())
+		}
+		if _, err = os.Stat(filePath + journalSuffix); err == nil {
+			return nil, errors.New("Incomplete Write Error: " + filePath + " is being appended to or an append was interrupted. It is restored by the next append.")
+		}
+		after, err := os.Stat(filePath)
+		if err == nil && after.Size() == before.Size() && after.ModTime().Equal(before.ModTime()) && int64(len(data)) == after.Size() {
+			return data, nil
+		}
+		if attempt == maxReadAttempts {
+			return nil, errors.New("Incomplete Write Error: " + filePath + " kept changing while it was read.")
+		}
 	}
-	r := csv.NewReader(bufio.NewReader(f))
+}
+
+func parseTickerData(in io.Reader, tickerConfig *ReadConfig, dateFormat string, loc *time.Location) (TickerData, error) {
+	var tickerData TickerData
+	r := csv.NewReader(bufio.NewReader(in))
 	result, err := r.ReadAll()
 	if err != nil {
 		return tickerData, err
 	}
+	if len(result) == 0 {
+		return tickerData, errors.New("Invalid CSV Header. The file is empty.")
+	}
 	header, err := getColumnPositions(result[0], tickerConfig.Filter)
 	if err != nil {
 		return tickerData, err
 	}
-	indexRange, err := getIndexRange(result, header, &tickerConfig.Range, csvReader.DateFormat)
+	indexRange, err := getIndexRange(result, header, &tickerConfig.Range, dateFormat, loc)
 	if err != nil {
 		return tickerData, err
 	}
@@ -46,7 +163,7 @@ func (csvReader CsvReader) readTickerData(symbol string, tickerConfig *ReadConfi
 	index := -1
 	for i := indexRange.begin; i < indexRange.end; i++ {
 		index++
-		err := tickerData.addFromRecords(result[i], header, index, csvReader.DateFormat)
+		err := tickerData.addFromRecords(result[i], header, index, dateFormat, loc)
 		if err != nil {
 			return tickerData, err
 		}
@@ -54,85 +171,163 @@ func (csvReader CsvReader) readTickerData(symbol string, tickerConfig *ReadConfi
 	return tickerData, nil
 }
 
-func (csvReader CsvReader) readEventData(event *Event) (EventData, error) {
+func parseEventData(in io.Reader, dateFormat string, loc *time.Location) (EventData, error) {
 	var eventData EventData
 	eventData.Date = make(map[time.Time]bool)
-	fileName := getEventDataFileName(csvReader.FileNamePattern, event.Name)
-	filePath := csvReader.DataPath + string(os.PathSeparator) + fileName
-	f, err := os.Open(filePath)
-	if err != nil {
-		return eventData, errors.New("File Open Error: " + err.Error())
-	}
-	r := csv.NewReader(bufio.NewReader(f))
+	r := csv.NewReader(bufio.NewReader(in))
 	result, err := r.ReadAll()
 	if err != nil {
 		return eventData, err
 	}
+	if len(result) == 0 {
+		return eventData, errors.New("Invalid CSV Header. Missing header item(s): date")
+	}
 	dataLength := len(result)
 	header, err := getColumnPositions(result[0], []string{"date"})
 	if err != nil {
 		return eventData, err
 	}
 	for i := 1; i < dataLength; i++ {
-		date, _ := time.Parse(csvReader.DateFormat, result[i][header["date"]])
+		date, _ := parseDate(dateFormat, result[i][header["date"]], loc)
 		eventData.Date[date] = true
-		if err == io.EOF {
-			break
-		}
 	}
 	return eventData, nil
 }
 
-func (csvReader CsvReader) readDividendData(symbol string, source DataSource) (TickerDividendData, error) {
+func parseDividendData(in io.Reader, source DataSource, dateFormat string, loc *time.Location) (TickerDividendData, error) {
 	var tickerDd TickerDividendData
-	fileName := getFileName(csvReader.FileNamePattern, "{ticker}", symbol)
-	filePath := csvReader.DataPath + string(os.PathSeparator) + fileName
-	f, err := os.Open(filePath)
-	if err != nil {
-		return tickerDd, errors.New("File Open Error: " + err.Error())
-	}
+	var err error
 	if source == YAHOO {
-		r := bufio.NewReader(f)
-		err = addFromYahooSplitDivData(&tickerDd, "dividend", r, csvReader.DateFormat)
+		r := bufio.NewReader(in)
+		err = addFromYahooSplitDivData(&tickerDd, "dividend", r, dateFormat, loc)
 	} else {
-		r := csv.NewReader(bufio.NewReader(f))
+		r := csv.NewReader(bufio.NewReader(in))
 		header := make(map[string]int)
 		header["date"] = 0
 		header["dividend"] = 1
-		err = addFromStandardCsvData(&tickerDd, header, r, csvReader.DateFormat)
+		err = addFromStandardCsvData(&tickerDd, header, r, dateFormat, loc)
 	}
 	return tickerDd, err
 }
 
-func (csvReader CsvReader) readSplitData(symbol string, source DataSource) (TickerSplitData, error) {
+func parseSplitData(in io.Reader, source DataSource, dateFormat string, loc *time.Location) (TickerSplitData, error) {
 	var tickerSd TickerSplitData
-	fileName := getFileName(csvReader.FileNamePattern, "{ticker}", symbol)
-	if fileName == "" {
-		return tickerSd, errors.New("File for ticker: '" + symbol + "' does not exist.")
-	}
-	filePath := csvReader.DataPath + string(os.PathSeparator) + fileName
-	f, err := os.Open(filePath)
-	if err != nil {
-		return tickerSd, errors.New("File Open Error: " + err.Error())
-	}
+	var err error
 	if source == YAHOO {
-		r := bufio.NewReader(f)
-		err = addFromYahooSplitDivData(&tickerSd, "split", r, csvReader.DateFormat)
+		r := bufio.NewReader(in)
+		err = addFromYahooSplitDivData(&tickerSd, "split", r, dateFormat, loc)
 	} else {
-		r := csv.NewReader(bufio.NewReader(f))
+		r := csv.NewReader(bufio.NewReader(in))
 		header := make(map[string]int)
 		header["date"] = 0
 		header["split"] = 1
-		err = addFromStandardCsvData(&tickerSd, header, r, csvReader.getDateFormat())
+		err = addFromStandardCsvData(&tickerSd, header, r, dateFormat, loc)
 	}
 	return tickerSd, err
 }
 
+func parseColumnarData(data Data, in io.Reader, config *ReadConfig, requiredFields []string, dateFormat string, loc *time.Location) error {
+	r := csv.NewReader(bufio.NewReader(in))
+	result, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return errors.New("Invalid CSV Header. Missing header item(s): " + strings.Join(requiredFields, ","))
+	}
+	header, err := getColumnPositions(result[0], config.Filter)
+	if err != nil {
+		return err
+	}
+	err = validateCsvHeader(header, requiredFields)
+	if err != nil {
+		return err
+	}
+	if _, exists := header["date"]; !exists {
+		if timestampIndex, exists := header["timestamp"]; exists {
+			header["date"] = timestampIndex
+			delete(header, "timestamp")
+		}
+	}
+	indexRange, err := getIndexRange(result, header, &config.Range, dateFormat, loc)
+	if err != nil {
+		return err
+	}
+	data.initialize(indexRange.end - indexRange.begin)
+	index := -1
+	for i := indexRange.begin; i < indexRange.end; i++ {
+		index++
+		err := data.addFromRecords(result[i], header, index, dateFormat, loc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (csvReader CsvReader) getDateFormat() string {
 	return csvReader.DateFormat
 }
 
-func addFromYahooSplitDivData(data Data, dataType string, r *bufio.Reader, dateFormat string) error {
+func (csvReader CsvReader) getLocation() *time.Location {
+	return csvReader.Location
+}
+
+func (csvReader CsvReader) sourceFileName(kind string, name string, timeFrame string) string {
+	var fileName string
+	switch kind {
+	case "ticker":
+		fileName = getTickerDataFileName(csvReader.FileNamePattern, name, timeFrame)
+	case "event":
+		fileName = getEventDataFileName(csvReader.FileNamePattern, name)
+	default:
+		fileName = getFileName(csvReader.FileNamePattern, "{ticker}", name)
+	}
+	return csvReader.DataPath + string(os.PathSeparator) + fileName
+}
+
+// sourceVersion identifies the content of a file by its size and hash.
+func (csvReader CsvReader) sourceStamp(kind string, name string, timeFrame string) (string, error) {
+	info, err := os.Stat(csvReader.sourceFileName(kind, name, timeFrame))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+func (csvReader CsvReader) sourceVersion(kind string, name string, timeFrame string) (string, error) {
+	f, err := os.Open(csvReader.sourceFileName(kind, name, timeFrame))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha1.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", size, hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// cacheIdentity holds every setting that changes the data read from a file.
+func (csvReader CsvReader) cacheIdentity() string {
+	dataPath, err := filepath.Abs(csvReader.DataPath)
+	if err != nil {
+		dataPath = csvReader.DataPath
+	}
+	location := ""
+	if csvReader.Location != nil {
+		location = csvReader.Location.String()
+	}
+	precision := "-"
+	if csvReader.PricePrecision != nil {
+		precision = fmt.Sprint(*csvReader.PricePrecision)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%v|%t", dataPath, csvReader.FileNamePattern, csvReader.DateFormat, location, precision,
+		csvReader.SymbolPrecision, csvReader.DetectPrecision)
+}
+
+func addFromYahooSplitDivData(data Data, dataType string, r *bufio.Reader, dateFormat string, loc *time.Location) error {
 	line, err := r.ReadString(10)
 	records := [][]string{}
 	var splitLine []string
@@ -153,7 +348,7 @@ func addFromYahooSplitDivData(data Data, dataType string, r *bufio.Reader, dateF
 	index := -1
 	for i := 0; i < size; i++ {
 		index++
-		err := data.addFromRecords(records[i], header, index, dateFormat)
+		err := data.addFromRecords(records[i], header, index, dateFormat, loc)
 		if err != nil {
 			return err
 		}
@@ -161,7 +356,7 @@ func addFromYahooSplitDivData(data Data, dataType string, r *bufio.Reader, dateF
 	return nil
 }
 
-func addFromStandardCsvData(data Data, header map[string]int, r *csv.Reader, dateFormat string) error {
+func addFromStandardCsvData(data Data, header map[string]int, r *csv.Reader, dateFormat string, loc *time.Location) error {
 	records, err := r.ReadAll()
 	if err != nil {
 		return err
@@ -171,7 +366,7 @@ func addFromStandardCsvData(data Data, header map[string]int, r *csv.Reader, dat
 	index := -1
 	for i := 1; i < size; i++ {
 		index++
-		err := data.addFromRecords(records[i], header, index, dateFormat)
+		err := data.addFromRecords(records[i], header, index, dateFormat, loc)
 		if err != nil {
 			return err
 		}
@@ -189,15 +384,23 @@ func getCountOfMatchingItems(records [][]string, pattern string, index int) int
 	}
 	return count
 }
-func getIndexRange(records [][]string, header map[string]int, dateRange *DateRange, dateFormat string) (indexRange, error) {
+
+// getIndexRange returns the records from the first one on StartDate or after it up to,
+// but excluding, the first one on EndDate or after it. A zero date leaves its side of
+// the range open, and a StartDate after all records gives an empty range. An EndDate
+// before StartDate is an error.
+func getIndexRange(records [][]string, header map[string]int, dateRange *DateRange, dateFormat string, loc *time.Location) (indexRange, error) {
 	dataLength := len(records)
 	var indexRange indexRange
 	var err error
-	if dateRange.StartDate.IsZero() {
+	if dateRange.StartDate.IsZero() && dateRange.EndDate.IsZero() {
 		indexRange.begin = 1
 		indexRange.end = dataLength
 		return indexRange, err
 	}
+	if !dateRange.EndDate.IsZero() && dateRange.EndDate.Before(dateRange.StartDate) {
+		return indexRange, errors.New("Invalid Date Range. The end date " + dateRange.EndDate.String() + " is before the start date " + dateRange.StartDate.String() + ".")
+	}
 	dateColumnIndex, exists := header["date"]
 	if !exists {
 		dateColumn, err := getColumnPositions(records[0], []string{"date"})
@@ -207,16 +410,20 @@ func getIndexRange(records [][]string, header map[string]int, dateRange *DateRan
 		dateColumnIndex = dateColumn["date"]
 	}
 	for i := 1; i < dataLength; i++ {
-		date, _ := time.Parse(dateFormat, records[i][dateColumnIndex])
+		date, _ := parseDate(dateFormat, records[i][dateColumnIndex], loc)
 		if indexRange.begin == 0 && (date.Equal(dateRange.StartDate) || date.After(dateRange.StartDate)) {
 			indexRange.begin = i
-		} else if date.Equal(dateRange.EndDate) || date.After(dateRange.EndDate) {
+		}
+		if indexRange.begin != 0 && !dateRange.EndDate.IsZero() && (date.Equal(dateRange.EndDate) || date.After(dateRange.EndDate)) {
 			indexRange.end = i
 			break
 		}
 	}
-	if &indexRange.end == nil && &indexRange.begin != nil {
-		indexRange.end = dataLength - 1
+	if indexRange.begin == 0 {
+		indexRange.begin = dataLength
+	}
+	if indexRange.end == 0 {
+		indexRange.end = dataLength
 	}
 
 	return indexRange, err
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test_getTickerDataFileName(t *testing.T) {
@@ -84,6 +85,30 @@ func Test_readYahooSplitData(t *testing.T) {
 	}
 }
 
+func Test_readCorporateActionDataWithWrongDateFormat(t *testing.T) {
+	var csvReader CsvReader
+	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	csvReader.DateFormat = "1/2/2006"
+	for _, source := range []DataSource{YAHOO, OTHER} {
+		csvReader.FileNamePattern = "{ticker}-splitdata.csv"
+		if source == YAHOO {
+			csvReader.FileNamePattern = "{ticker}-yahoosplitdividend.csv"
+		}
+		if _, err := csvReader.readSplitData("someticker", source); err == nil {
+			t.Log("Test_readCorporateActionDataWithWrongDateFormat should fail to read splits of source ", source)
+			t.Fail()
+		}
+		csvReader.FileNamePattern = "{ticker}-dividenddata.csv"
+		if source == YAHOO {
+			csvReader.FileNamePattern = "{ticker}-yahoosplitdividend.csv"
+		}
+		if _, err := csvReader.readDividendData("someticker", source); err == nil {
+			t.Log("Test_readCorporateActionDataWithWrongDateFormat should fail to read dividends of source ", source)
+			t.Fail()
+		}
+	}
+}
+
 func Test_readStandardSplitData(t *testing.T) {
 	var csvReader CsvReader
 	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
@@ -174,3 +199,88 @@ func Test_readEventDataHandlesErrors(t *testing.T) {
 		}
 	}
 }
+
+func Test_readTickerDataSetsPricePrecision(t *testing.T) {
+	testCases := []struct {
+		name            string
+		symbol          string
+		dateFormat      string
+		precision       *int
+		symbolPrecision map[string]int
+		detect          bool
+		expectedValue   int
+	}{
+		{"'Default precision'", "someticker", "1/2/2006", nil, nil, false, 2},
+		{"'Reader precision'", "someticker", "1/2/2006", NewPrecision(4), nil, false, 4},
+		{"'Zero precision'", "someticker", "1/2/2006", NewPrecision(0), nil, false, 0},
+		{"'Symbol precision before reader precision'", "someticker", "1/2/2006", NewPrecision(4), map[string]int{"someticker": 3}, true, 3},
+		{"'Detected precision'", "spy", "2006-01-02", nil, map[string]int{"someticker": 3}, true, 6},
+	}
+	for _, tc := range testCases {
+		var csvReader CsvReader
+		csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+		csvReader.FileNamePattern = "{ticker}-{timeframe}.csv"
+		csvReader.DateFormat = tc.dateFormat
+		csvReader.PricePrecision = tc.precision
+		csvReader.SymbolPrecision = tc.symbolPrecision
+		csvReader.DetectPrecision = tc.detect
+		result, err := csvReader.readTickerData(tc.symbol, &ReadConfig{TimeFrame: "daily"})
+		if err != nil || result.pricePrecision() != tc.expectedValue {
+			t.Log("Test_readTickerDataSetsPricePrecision test case ", tc.name, " failed. Result was: ", result.pricePrecision(), " but should be: ", tc.expectedValue, " Error: ", err)
+			t.Fail()
+		}
+	}
+}
+
+func Test_readTickerDataInExchangeLocation(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	var csvReader CsvReader
+	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	csvReader.FileNamePattern = "{ticker}-{timeframe}.csv"
+	csvReader.DateFormat = time.RFC3339
+	csvReader.Location = tokyo
+	result, err := csvReader.readTickerData("tokyo", &ReadConfig{TimeFrame: "daily"})
+	result = ProcessRawTickerData(&result, &TickerSplitData{}, "daily", []string{"id", "weekly_id", "monthly_id"}, []string{"weekly", "monthly"})
+	expectedWeekdays := []time.Weekday{time.Friday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday}
+	for i, weekday := range expectedWeekdays {
+		if result.Date[i].Weekday() != weekday {
+			t.Log("Failed to read dates in exchange location. Weekday was: ", result.Date[i].Weekday(), " but should be: ", weekday)
+			t.Fail()
+		}
+	}
+	expectedWeeklyIds := []int32{-1, 0, 0, 0, 0}
+	expectedMonthlyIds := []int32{-1, -1, -1, 0, 0}
+	if err != nil || !reflect.DeepEqual(result.HigherTfIds["weekly_id"], expectedWeeklyIds) || !reflect.DeepEqual(result.HigherTfIds["monthly_id"], expectedMonthlyIds) {
+		t.Log("Failed to build ids in exchange location. Result was: ", result.HigherTfIds, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestGetIndexRange(t *testing.T) {
+	records := [][]string{{"date", "close"}, {"12/7/2016", "1"}, {"12/8/2016", "2"}, {"12/9/2016", "3"}}
+	header := map[string]int{"date": 0, "close": 1}
+	dates := createDates([]string{"12/7/2016", "12/8/2016", "12/9/2016", "12/10/2016"}, "1/2/2006")
+	testCases := []struct {
+		name           string
+		dateRange      DateRange
+		expectedResult indexRange
+	}{
+		{"'No range'", DateRange{}, indexRange{1, 4}},
+		{"'Start date only'", DateRange{StartDate: dates[1]}, indexRange{2, 4}},
+		{"'End date only'", DateRange{EndDate: dates[1]}, indexRange{1, 2}},
+		{"'Start and end date'", DateRange{StartDate: dates[0], EndDate: dates[2]}, indexRange{1, 3}},
+		{"'Start date after the data'", DateRange{StartDate: dates[3]}, indexRange{4, 4}},
+		{"'Same start and end date'", DateRange{StartDate: dates[1], EndDate: dates[1]}, indexRange{2, 2}},
+	}
+	for _, tc := range testCases {
+		result, err := getIndexRange(records, header, &tc.dateRange, "1/2/2006", nil)
+		if err != nil || result != tc.expectedResult {
+			t.Log("TestGetIndexRange test case ", tc.name, " failed. Result was: ", result, " but should be: ", tc.expectedResult, " Error: ", err)
+			t.Fail()
+		}
+	}
+	if _, err := getIndexRange(records, header, &DateRange{StartDate: dates[2], EndDate: dates[0]}, "1/2/2006", nil); err == nil {
+		t.Log("TestGetIndexRange should fail for an end date before the start date.")
+		t.Fail()
+	}
+}
@@ -3,71 +3,448 @@ package marketdata
 import (
 	"bufio"
 	"bytes"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type CsvWriter struct {
 	OutputPath      string
 	FileNamePattern string
 	DateFormat      string
+	Location        *time.Location
+	LockTimeout     time.Duration
 }
 
 func (csvWriter CsvWriter) writeTickerData(symbol string, tickerData *TickerData, tickerConfig *WriteConfig) error {
 	newLine := "\n"
 	fileName := getTickerDataFileName(csvWriter.FileNamePattern, symbol, tickerConfig.TimeFrame)
-	filePath := csvWriter.OutputPath
-	var fwr, fr *os.File
-	var err error
-	var nextId int
-	fileOpenError := false
-	newFile := false
+	sortedHigherTfIds := getSortedHigherTimeFrameIds(tickerData.HigherTfIds)
 	if tickerConfig.Append {
-		fr, err = os.Open(filePath + fileName)
+		appended, err := csvWriter.appendToFile(fileName, tickerData, sortedHigherTfIds)
+		if appended || err != nil {
+			return err
+		}
+	}
+	fwr, err := csvWriter.createFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer fwr.Close()
+	writer := bufio.NewWriter(fwr)
+	printHeader(writer, tickerData, sortedHigherTfIds, newLine)
+	printTickerData(writer, tickerData, sortedHigherTfIds, 0, newLine, csvWriter.DateFormat, csvWriter.Location)
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	return fwr.commit()
+}
+
+// appendToFile appends to fileName when it exists and reports whether it did. An
+// append interrupted by a crash is rolled back first.
+func (csvWriter CsvWriter) appendToFile(fileName string, tickerData *TickerData, sortedHigherTfIds []string) (bool, error) {
+	filePath := csvWriter.OutputPath + fileName
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return false, nil
+	}
+	unlock, err := lockFile(filePath, csvWriter.LockTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if err = recoverAppend(filePath); err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return false, errors.New("File Open Error: " + err.Error())
+	}
+	defer f.Close()
+	return true, csvWriter.appendTickerData(f, filePath, tickerData, sortedHigherTfIds)
+}
+
+// appendPosition is where the bars following lastDate are written to a stored ticker
+// data file. A last row marked as incomplete is overwritten, so lastDate is the date
+// of the row before it. Records holds the stored rows up to lastDate without the header.
+type appendPosition struct {
+	offset       int64
+	lastDate     time.Time
+	writeHeader  bool
+	newLine      string
+	needsNewLine bool
+	records      [][]string
+}
+
+// appendTickerData writes the bars of tickerData after the last complete row of f and
+// overwrites a last row marked as incomplete, such as a weekly bar of an unfinished week.
+// Bars of tickerData that are already stored have to match the stored rows, and the
+// ids and linked higher time frame ids of the written bars continue the stored ones.
+// The overwritten bytes are kept in a journal until the appended data is synced.
+func (csvWriter CsvWriter) appendTickerData(f *os.File, filePath string, tickerData *TickerData, sortedHigherTfIds []string) error {
+	var buffer bytes.Buffer
+	headerWriter := bufio.NewWriter(&buffer)
+	printHeader(headerWriter, tickerData, sortedHigherTfIds, "")
+	headerWriter.Flush()
+	header := strings.Split(buffer.String(), ",")
+	pos, err := csvWriter.getAppendPosition(f, header)
+	if err != nil {
+		return err
+	}
+	first := 0
+	for !pos.lastDate.IsZero() && first < len(tickerData.Date) && !csvWriter.normalizeDate(tickerData.Date[first]).After(pos.lastDate) {
+		first++
+	}
+	for i := first + 1; i < len(tickerData.Date); i++ {
+		if !tickerData.Date[i].After(tickerData.Date[i-1]) {
+			return errors.New("Append Error: The dates to append are not in ascending order or contain duplicates at " + formatDate(tickerData.Date[i], csvWriter.DateFormat, csvWriter.Location) + ".")
+		}
+	}
+	if err = csvWriter.compareStoredRows(tickerData, first, &pos, header); err != nil {
+		return err
+	}
+	appendTd, err := csvWriter.continueIds(tickerData, first, &pos, header)
+	if err != nil {
+		return err
+	}
+	if err = writeJournal(f, filePath, pos.offset); err != nil {
+		return err
+	}
+	if err = f.Truncate(pos.offset); err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	if _, err = f.Seek(pos.offset, io.SeekStart); err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	writer := bufio.NewWriter(f)
+	if pos.needsNewLine {
+		writer.WriteString(pos.newLine)
+	}
+	if pos.writeHeader {
+		printHeader(writer, tickerData, sortedHigherTfIds, pos.newLine)
+	}
+	printTickerData(writer, &appendTd, sortedHigherTfIds, 0, pos.newLine, csvWriter.DateFormat, csvWriter.Location)
+	if err = writer.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		return errors.New("File Write Error: " + err.Error())
+	}
+	return os.Remove(filePath + journalSuffix)
+}
+
+// getAppendPosition reads a stored ticker data file, which may use CRLF line endings,
+// lack a trailing newline or have no header. A header has to match the header of the
+// data to append. Files without a header are expected to have its columns.
+func (csvWriter CsvWriter) getAppendPosition(f *os.File, header []string) (appendPosition, error) {
+	pos := appendPosition{newLine: "\n"}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return pos, errors.New("File Read Error: " + err.Error())
+	}
+	if bytes.Contains(data, []byte("\r\n")) {
+		pos.newLine = "\r\n"
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	var records [][]string
+	var offsets []int64
+	for {
+		offset := r.InputOffset()
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			fileOpenError = true
+			return pos, errors.New("File Read Error: " + err.Error())
 		}
+		records = append(records, record)
+		offsets = append(offsets, offset)
+	}
+	if len(records) == 0 {
+		pos.writeHeader = true
+		return pos, nil
+	}
+	column := map[string]int{}
+	for i, field := range header {
+		column[field] = i
+	}
+	begin := 0
+	if inArray("date", lowerCase(records[0])) {
+		if strings.Join(lowerCase(records[0]), ",") != strings.Join(header, ",") {
+			return pos, errors.New("Append Error: The stored header " + strings.Join(records[0], ",") + " does not match the header " + strings.Join(header, ",") + " of the data to append.")
+		}
+		begin = 1
+	}
+	last := len(records) - 1
+	pos.offset = int64(len(data))
+	pos.needsNewLine = len(data) > 0 && data[len(data)-1] != '\n'
+	if index, exists := column["incomplete"]; exists && last >= begin && index < len(records[last]) && records[last][index] == "true" {
+		pos.offset = offsets[last]
+		pos.needsNewLine = false
+		last--
+	}
+	if last < begin {
+		return pos, nil
 	}
-	if tickerConfig.Append && !fileOpenError {
-		nextId, err = getNextId(fr)
-		fr.Close()
-		fwr, err = os.OpenFile(filePath+fileName, os.O_APPEND|os.O_WRONLY, 0600)
+	pos.records = records[begin : last+1]
+	dateIndex, exists := column["date"]
+	if !exists || dateIndex >= len(records[last]) {
+		return pos, errors.New("Append Error: The stored data has no date column.")
+	}
+	pos.lastDate, err = parseDate(csvWriter.DateFormat, records[last][dateIndex], csvWriter.Location)
+	if err != nil {
+		return pos, errors.New("Append Error: " + err.Error())
+	}
+	return pos, nil
+}
+
+// compareStoredRows checks that the bars of td before first, which are not appended,
+// equal the stored rows of their dates. Ids are not compared, as continueIds renumbers
+// them. Bars dated before the first stored row are not checked.
+func (csvWriter CsvWriter) compareStoredRows(td *TickerData, first int, pos *appendPosition, header []string) error {
+	if first == 0 {
+		return nil
+	}
+	dateIndex := 0
+	for j, field := range header {
+		if field == "date" {
+			dateIndex = j
+		}
+	}
+	stored := make(map[int64][]string)
+	var firstDate time.Time
+	for i, record := range pos.records {
+		if dateIndex >= len(record) {
+			return errors.New("Append Error: A stored row has no date.")
+		}
+		date, err := parseDate(csvWriter.DateFormat, record[dateIndex], csvWriter.Location)
 		if err != nil {
-			return errors.New("File Write Error: " + err.Error())
+			return errors.New("Append Error: " + err.Error())
 		}
-	} else {
-		newFile = true
-		os.MkdirAll(filePath, os.ModePerm)
-		fwr, err = os.Create(filePath + fileName)
+		if i == 0 {
+			firstDate = date
+		}
+		stored[date.UnixNano()] = record
+	}
+	for i := 0; i < first; i++ {
+		date := csvWriter.normalizeDate(td.Date[i])
+		if date.Before(firstDate) {
+			continue
+		}
+		formattedDate := formatDate(td.Date[i], csvWriter.DateFormat, csvWriter.Location)
+		record, exists := stored[date.UnixNano()]
+		if !exists {
+			return errors.New("Append Error: The bar of " + formattedDate + " is not stored.")
+		}
+		var buffer bytes.Buffer
+		writer := bufio.NewWriter(&buffer)
+		printTickerDataItem(writer, td, getSortedHigherTimeFrameIds(td.HigherTfIds), i, "", csvWriter.DateFormat, csvWriter.Location)
+		writer.Flush()
+		values := strings.Split(buffer.String(), ",")
+		for j, field := range header {
+			if field == "id" || strings.HasSuffix(field, "_id") || j >= len(record) || equalCsvValues(values[j], record[j]) {
+				continue
+			}
+			return errors.New("Append Error: The bar of " + formattedDate + " has " + field + " " + values[j] + " but " + record[j] + " is stored.")
+		}
+	}
+	return nil
+}
+
+// continueIds returns the bars of td from first on with their id and linked higher time
+// frame ids shifted to continue the stored rows. The shift is taken from the last stored
+// row when td has its date, and otherwise the bar at first gets the next id and the
+// linked id of the last stored row unless it starts a new higher time frame period.
+func (csvWriter CsvWriter) continueIds(td *TickerData, first int, pos *appendPosition, header []string) (TickerData, error) {
+	appendTd := copyTickerDataRange(td, first, len(td.Date))
+	if len(pos.records) == 0 || first == len(td.Date) {
+		return appendTd, nil
+	}
+	last := pos.records[len(pos.records)-1]
+	overlaps := first > 0 && csvWriter.normalizeDate(td.Date[first-1]).Equal(pos.lastDate)
+	for j, field := range header {
+		var ids, appendIds []int32
+		if field == "id" {
+			ids, appendIds = td.Id, appendTd.Id
+		} else if strings.HasSuffix(field, "_id") {
+			ids, appendIds = td.HigherTfIds[field], appendTd.HigherTfIds[field]
+		} else {
+			continue
+		}
+		if j >= len(last) {
+			return appendTd, errors.New("Append Error: The last stored row has no " + field + ".")
+		}
+		storedId, err := strconv.ParseInt(last[j], 10, 32)
 		if err != nil {
-			return errors.New("File Write Error: " + err.Error())
+			return appendTd, errors.New("Append Error: The last stored row has an invalid " + field + " " + last[j] + ".")
+		}
+		var shift int32
+		if overlaps {
+			shift = int32(storedId) - ids[first-1]
+		} else if field == "id" {
+			shift = int32(storedId) + 1 - ids[first]
+		} else {
+			higherTf := strings.TrimSuffix(field, "_id")
+			cal := td.calendar()
+			shift = int32(storedId) - ids[first]
+			if cal.periodKey(pos.lastDate, higherTf) != cal.periodKey(td.Date[first], higherTf) {
+				shift++
+			}
 		}
-		nextId = 0
+		for i := range appendIds {
+			appendIds[i] += shift
+		}
+	}
+	return appendTd, nil
+}
+
+func equalCsvValues(value string, stored string) bool {
+	if value == stored {
+		return true
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	storedNumber, storedErr := strconv.ParseFloat(stored, 64)
+	return err == nil && storedErr == nil && number == storedNumber
+}
+
+// normalizeDate drops the part of date its date format does not store, so it can be
+// compared with a stored date.
+func (csvWriter CsvWriter) normalizeDate(date time.Time) time.Time {
+	normalized, err := parseDate(csvWriter.DateFormat, formatDate(date, csvWriter.DateFormat, csvWriter.Location), csvWriter.Location)
+	if err != nil {
+		return date
+	}
+	return normalized
+}
+
+func (csvWriter CsvWriter) writeEventData(event *Event, eventData *EventData) error {
+	newLine := "\n"
+	fwr, err := csvWriter.createFile(getEventDataFileName(csvWriter.FileNamePattern, event.Name))
+	if err != nil {
+		return err
 	}
 	defer fwr.Close()
 	writer := bufio.NewWriter(fwr)
-	sortedHigherTfIds := getSortedHigherTimeFrameIds(tickerData.HigherTfIds)
-	if newFile {
-		printHeader(writer, tickerData, sortedHigherTfIds, newLine)
+	fmt.Fprintf(writer, "Date%v", newLine)
+	for _, date := range getSortedEventDates(eventData) {
+		fmt.Fprintf(writer, "%v%v", formatDate(date, csvWriter.DateFormat, csvWriter.Location), newLine)
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	return fwr.commit()
+}
+
+func (csvWriter CsvWriter) writeSplitData(symbol string, tsd *TickerSplitData, source DataSource) error {
+	if source == YAHOO {
+		return csvWriter.writeYahooFile(symbol, tsd, nil)
+	}
+	newLine := "\n"
+	fwr, err := csvWriter.createFile(getFileName(csvWriter.FileNamePattern, "{ticker}", symbol))
+	if err != nil {
+		return err
+	}
+	defer fwr.Close()
+	writer := bufio.NewWriter(fwr)
+	fmt.Fprintf(writer, "Date,Split%v", newLine)
+	l := len(tsd.Date)
+	for i := 0; i < l; i++ {
+		fmt.Fprintf(writer, "%v,%v%v", formatDate(tsd.Date[i], csvWriter.DateFormat, csvWriter.Location), formatSplit(tsd, i), newLine)
 	}
-	printTickerData(writer, tickerData, sortedHigherTfIds, nextId, newLine, csvWriter.DateFormat)
-	writer.Flush()
-	return err
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	return fwr.commit()
 }
 
-func printTickerData(writer *bufio.Writer, tickerData *TickerData, sortedHigherTfIds []string, nextId int, newLine string, dateFormat string) {
+func (csvWriter CsvWriter) writeDividendData(symbol string, tdd *TickerDividendData, source DataSource) error {
+	if source == YAHOO {
+		return csvWriter.writeYahooFile(symbol, nil, tdd)
+	}
+	newLine := "\n"
+	fwr, err := csvWriter.createFile(getFileName(csvWriter.FileNamePattern, "{ticker}", symbol))
+	if err != nil {
+		return err
+	}
+	defer fwr.Close()
+	writer := bufio.NewWriter(fwr)
+	fmt.Fprintf(writer, "Date,Dividend%v", newLine)
+	l := len(tdd.Date)
+	for i := 0; i < l; i++ {
+		fmt.Fprintf(writer, "%v,%v%v", formatDate(tdd.Date[i], csvWriter.DateFormat, csvWriter.Location), formatDividend(tdd, i), newLine)
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	return fwr.commit()
+}
+
+func (csvWriter CsvWriter) writeYahooSplitDividendData(symbol string, tsd *TickerSplitData, tdd *TickerDividendData) error {
+	return csvWriter.writeYahooFile(symbol, tsd, tdd)
+}
+
+// writeYahooFile writes a combined Yahoo file. When tsd or tdd is nil the splits or
+// dividends already stored in the file are kept.
+func (csvWriter CsvWriter) writeYahooFile(symbol string, tsd *TickerSplitData, tdd *TickerDividendData) error {
+	newLine := "\n"
+	fileName := getFileName(csvWriter.FileNamePattern, "{ticker}", symbol)
+	fwr, err := csvWriter.createFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer fwr.Close()
+	if tsd == nil || tdd == nil {
+		stored, err := ioutil.ReadFile(csvWriter.OutputPath + fileName)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.New("File Read Error: " + err.Error())
+		}
+		if tsd == nil {
+			storedTsd, err := parseSplitData(bytes.NewReader(stored), YAHOO, csvWriter.DateFormat, csvWriter.Location)
+			if err != nil {
+				return err
+			}
+			tsd = &storedTsd
+		} else {
+			storedTdd, err := parseDividendData(bytes.NewReader(stored), YAHOO, csvWriter.DateFormat, csvWriter.Location)
+			if err != nil {
+				return err
+			}
+			tdd = &storedTdd
+		}
+	}
+	writer := bufio.NewWriter(fwr)
+	fmt.Fprintf(writer, "Date,Dividends%v", newLine)
+	for _, record := range getYahooSplitDividendRecords(tsd, tdd, csvWriter.DateFormat, csvWriter.Location) {
+		fmt.Fprintf(writer, "%v%v", record, newLine)
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	return fwr.commit()
+}
+
+func (csvWriter CsvWriter) createFile(fileName string) (*atomicFile, error) {
+	filePath := csvWriter.OutputPath
+	os.MkdirAll(filePath, os.ModePerm)
+	return createAtomicFile(filePath+fileName, csvWriter.LockTimeout)
+}
+
+func printTickerData(writer *bufio.Writer, tickerData *TickerData, sortedHigherTfIds []string, nextId int, newLine string, dateFormat string, loc *time.Location) {
 	l := len(tickerData.Date)
 	var i int
 	for i = nextId; i < l; i++ {
-		printTickerDataItem(writer, tickerData, sortedHigherTfIds, i, newLine, dateFormat)
+		printTickerDataItem(writer, tickerData, sortedHigherTfIds, i, newLine, dateFormat, loc)
 	}
 }
 
-func printTickerDataItem(writer *bufio.Writer, td *TickerData, sortedHigherTfIds []string, index int, newLine string, dateFormat string) {
+func printTickerDataItem(writer *bufio.Writer, td *TickerData, sortedHigherTfIds []string, index int, newLine string, dateFormat string, loc *time.Location) {
 	record := ""
 	if td.Id != nil {
 		record = record + fmt.Sprintf("%v", td.Id[index]) + ","
@@ -78,7 +455,7 @@ func printTickerDataItem(writer *bufio.Writer, td *TickerData, sortedHigherTfIds
 		}
 	}
 	if td.Date != nil {
-		record = record + td.Date[index].Format(dateFormat) + ","
+		record = record + formatDate(td.Date[index], dateFormat, loc) + ","
 	}
 	if td.Open != nil {
 		record = record + fmt.Sprintf("%v", td.Open[index]) + ","
@@ -95,6 +472,24 @@ func printTickerDataItem(writer *bufio.Writer, td *TickerData, sortedHigherTfIds
 	if td.Volume != nil {
 		record = record + fmt.Sprintf("%v", td.Volume[index]) + ","
 	}
+	if td.OpenInterest != nil {
+		record = record + fmt.Sprintf("%v", td.OpenInterest[index]) + ","
+	}
+	if td.AdjFactor != nil {
+		record = record + fmt.Sprintf("%v", td.AdjFactor[index]) + ","
+	}
+	if td.VolumeFactor != nil {
+		record = record + fmt.Sprintf("%v", td.VolumeFactor[index]) + ","
+	}
+	if td.Vwap != nil {
+		record = record + fmt.Sprintf("%v", td.Vwap[index]) + ","
+	}
+	if td.BarCount != nil {
+		record = record + fmt.Sprintf("%v", td.BarCount[index]) + ","
+	}
+	if td.Incomplete != nil {
+		record = record + fmt.Sprintf("%v", td.Incomplete[index]) + ","
+	}
 	fmt.Fprintf(writer, "%v%v", strings.TrimSuffix(record, ","), newLine)
 }
 
@@ -126,6 +521,24 @@ func printHeader(writer *bufio.Writer, td *TickerData, sortedHigherTfIds []strin
 	if td.Volume != nil {
 		header = header + "volume,"
 	}
+	if td.OpenInterest != nil {
+		header = header + "open_interest,"
+	}
+	if td.AdjFactor != nil {
+		header = header + "adj_factor,"
+	}
+	if td.VolumeFactor != nil {
+		header = header + "volume_factor,"
+	}
+	if td.Vwap != nil {
+		header = header + "vwap,"
+	}
+	if td.BarCount != nil {
+		header = header + "bar_count,"
+	}
+	if td.Incomplete != nil {
+		header = header + "incomplete,"
+	}
 	fmt.Fprintf(writer, "%v%v", strings.TrimSuffix(header, ","), newLine)
 }
 
@@ -140,23 +553,49 @@ func getSortedHigherTimeFrameIds(higherTfIds map[string][]int32) []string {
 	return sortedHigherTfIds
 }
 
-func getNextId(r io.Reader) (int, error) {
-	id, err := lineCounter(r)
-	return id - 1, err
+func getYahooSplitDividendRecords(tsd *TickerSplitData, tdd *TickerDividendData, dateFormat string, loc *time.Location) []string {
+	type yahooRecord struct {
+		date  time.Time
+		value string
+	}
+	records := []yahooRecord{}
+	for i := range tsd.Date {
+		records = append(records, yahooRecord{tsd.Date[i], "SPLIT, " + formatDate(tsd.Date[i], dateFormat, loc) + "," + formatSplit(tsd, i)})
+	}
+	for i := range tdd.Date {
+		records = append(records, yahooRecord{tdd.Date[i], "DIVIDEND, " + formatDate(tdd.Date[i], dateFormat, loc) + "," + formatDividend(tdd, i)})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].date.After(records[j].date) })
+	lines := make([]string, len(records))
+	for i, record := range records {
+		lines[i] = record.value
+	}
+	return lines
+}
+
+func formatSplit(tsd *TickerSplitData, index int) string {
+	return fmt.Sprintf("%v:%v", tsd.AfterSplitQty[index], tsd.BeforeSplitQty[index])
 }
 
-func lineCounter(r io.Reader) (int, error) {
-	buf := make([]byte, 32*1024)
-	count := 0
-	lineSep := []byte{'\n'}
-	for {
-		c, err := r.Read(buf)
-		count += bytes.Count(buf[:c], lineSep)
-		switch {
-		case err == io.EOF:
-			return count, nil
-		case err != nil:
-			return count, err
+func formatDividend(tdd *TickerDividendData, index int) string {
+	return strconv.FormatFloat(tdd.Amount[index], 'f', -1, 64)
+}
+
+func getSortedEventDates(eventData *EventData) []time.Time {
+	dates := make([]time.Time, 0, len(eventData.Date))
+	for date, occurred := range eventData.Date {
+		if occurred {
+			dates = append(dates, date)
 		}
 	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+func lowerCase(values []string) []string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return result
 }
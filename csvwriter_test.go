@@ -3,7 +3,9 @@ package marketdata
 import (
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_writeTickerData(t *testing.T) {
@@ -19,14 +21,14 @@ func Test_writeTickerData(t *testing.T) {
 	var processedTd TickerData
 	var expectedValue string
 	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
-	csvWriter := CsvWriter{outputPath, "{ticker}-{timeframe}.csv", "1/2/2006"}
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
 	symbol := "testticker"
 	baseTimeFrame := "daily"
 	var err error
 	var result []byte
 	resultingFile := outputPath + symbol + "-" + baseTimeFrame + ".csv"
 	for _, tc := range testCases {
-		tickerConfig := WriteConfig{"daily", tc.append}
+		tickerConfig := WriteConfig{TimeFrame: "daily", Append: tc.append}
 		if tc.fileExists {
 			processedTd = getExpectedDailyData()
 			tdSlice := getTickerDataSlice(&processedTd, 1)
@@ -153,3 +155,169 @@ func getExpectedCsvDailyDataForAppendTest() string {
 		"23,12/30/2016,226.02,226.73,226,226.27,1111\n" +
 		"24,1/2/2017,226.02,226.73,226,226.27,41054400\n"
 }
+
+func Test_writeSplitAndDividendData(t *testing.T) {
+	testCases := []struct {
+		name          string
+		inputPattern  string
+		outputPattern string
+		source        DataSource
+		dataType      string
+		expectedValue string
+	}{
+		{"'Write standard split data'", "{ticker}-splitdata.csv", "{ticker}-splitdata.csv", OTHER, "split", "Date,Split\n20020605,3:2\n20050609,2:1\n"},
+		{"'Write standard dividend data'", "{ticker}-dividenddata.csv", "{ticker}-dividenddata.csv", OTHER, "dividend",
+			"Date,Dividend\n20011214,0.135\n20020308,0.0575\n20050324,0.274\n20050620,0.146\n"},
+		{"'Write yahoo split data'", "{ticker}-yahoosplitdividend.csv", "{ticker}-yahoosplit.csv", YAHOO, "split",
+			"Date,Dividends\nSPLIT, 20050609,2:1\nSPLIT, 20020605,3:2\n"},
+		{"'Write yahoo split and dividend data'", "{ticker}-yahoosplitdividend.csv", "{ticker}-yahoosplitdividend.csv", YAHOO, "splitdividend",
+			"Date,Dividends\nDIVIDEND, 20050620,0.146\nSPLIT, 20050609,2:1\nDIVIDEND, 20050324,0.274\nSPLIT, 20020605,3:2\nDIVIDEND, 20020308,0.0575\nDIVIDEND, 20011214,0.135\n"},
+	}
+	inputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	outputPath := inputPath + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
+	dateFormat := "20060102"
+	symbol := "someticker"
+	var err error
+	for _, tc := range testCases {
+		csvReader := CsvReader{DataPath: inputPath, FileNamePattern: tc.inputPattern, DateFormat: dateFormat}
+		csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: tc.outputPattern, DateFormat: dateFormat}
+		if tc.dataType == "split" {
+			tsd, _ := ReadSplitData(csvReader, symbol, tc.source)
+			err = WriteSplitData(csvWriter, symbol, &tsd, tc.source)
+		} else if tc.dataType == "dividend" {
+			tdd, _ := ReadDividendData(csvReader, symbol, tc.source)
+			err = WriteDividendData(csvWriter, symbol, &tdd, tc.source)
+		} else {
+			tsd, _ := ReadSplitData(csvReader, symbol, tc.source)
+			tdd, _ := ReadDividendData(csvReader, symbol, tc.source)
+			err = WriteYahooSplitDividendData(csvWriter, symbol, &tsd, &tdd)
+		}
+		resultingFile := outputPath + getFileName(tc.outputPattern, "{ticker}", symbol)
+		result, _ := ioutil.ReadFile(resultingFile)
+		if err != nil || string(result) != tc.expectedValue {
+			t.Log("Test_writeSplitAndDividendData test case ", tc.name, " failed. Result was: ", string(result), " but should be: ", tc.expectedValue, " Error: ", err)
+			t.Fail()
+		}
+		os.Remove(resultingFile)
+	}
+}
+
+func Test_writeYahooDataKeepsOtherActionType(t *testing.T) {
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-yahoo.csv", DateFormat: "20060102"}
+	var tsd TickerSplitData
+	tsd.Date = createDates([]string{"20050609"}, "20060102")
+	tsd.BeforeSplitQty = []int{1}
+	tsd.AfterSplitQty = []int{2}
+	var tdd TickerDividendData
+	tdd.Date = createDates([]string{"20050620"}, "20060102")
+	tdd.Amount = []float64{0.1234567}
+	err := WriteSplitData(csvWriter, "someticker", &tsd, YAHOO)
+	if err == nil {
+		err = WriteDividendData(csvWriter, "someticker", &tdd, YAHOO)
+	}
+	tsd.AfterSplitQty = []int{3}
+	if err == nil {
+		err = WriteSplitData(csvWriter, "someticker", &tsd, YAHOO)
+	}
+	result, _ := ioutil.ReadFile(outputPath + "someticker-yahoo.csv")
+	expectedValue := "Date,Dividends\nDIVIDEND, 20050620,0.1234567\nSPLIT, 20050609,3:1\n"
+	if err != nil || string(result) != expectedValue {
+		t.Log("Test_writeYahooDataKeepsOtherActionType failed. Result was: ", string(result), " but should be: ", expectedValue, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func Test_writeTickerDataInExchangeLocation(t *testing.T) {
+	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006", Location: time.FixedZone("JST", 9*60*60)}
+	var td TickerData
+	td.Date = createDates([]string{"2017-01-29T15:00:00Z"}, time.RFC3339)
+	td.Close = []float64{19460}
+	err := csvWriter.writeTickerData("tokyo", &td, &WriteConfig{TimeFrame: "daily", Append: false})
+	resultingFile := outputPath + "tokyo-daily.csv"
+	result, _ := ioutil.ReadFile(resultingFile)
+	expectedValue := "date,close\n1/30/2017,19460\n"
+	if err != nil || string(result) != expectedValue {
+		t.Log("Failed to write dates in exchange location. Result was: ", string(result), " but should be: ", expectedValue)
+		t.Fail()
+	}
+	os.Remove(resultingFile)
+}
+
+func Test_writeTickerDataAppendsIncrementally(t *testing.T) {
+	var daily, weekly TickerData
+	daily.Id = []int32{0, 1, 2}
+	daily.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017"}, "1/2/2006")
+	daily.Close = []float64{1, 2, 3}
+	weekly.Id = []int32{0, 1, 2}
+	weekly.Date = createDates([]string{"1/2/2017", "1/9/2017", "1/16/2017"}, "1/2/2006")
+	weekly.Close = []float64{1, 2.5, 3}
+	weekly.BarCount = []int32{5, 5, 1}
+	weekly.Incomplete = []bool{false, false, true}
+	var duplicates TickerData
+	duplicates.Id = []int32{0, 1, 2}
+	duplicates.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/4/2017"}, "1/2/2006")
+	duplicates.Close = []float64{1, 2, 3}
+	var partial, newOnly, changed, linked, linkedNewOnly TickerData
+	partial.Id = []int32{0, 1}
+	partial.Date = createDates([]string{"1/4/2017", "1/5/2017"}, "1/2/2006")
+	partial.Close = []float64{2, 3}
+	newOnly.Id = []int32{0}
+	newOnly.Date = createDates([]string{"1/5/2017"}, "1/2/2006")
+	newOnly.Close = []float64{3}
+	changed.Id = []int32{0, 1}
+	changed.Date = partial.Date
+	changed.Close = []float64{99, 3}
+	linked.Id = []int32{0, 1}
+	linked.HigherTfIds = map[string][]int32{"weekly_id": {-1, 0}}
+	linked.Date = createDates([]string{"1/13/2017", "1/16/2017"}, "1/2/2006")
+	linked.Close = []float64{2, 3}
+	linkedNewOnly.Id = []int32{0}
+	linkedNewOnly.HigherTfIds = map[string][]int32{"weekly_id": {-1}}
+	linkedNewOnly.Date = linked.Date[1:]
+	linkedNewOnly.Close = []float64{3}
+	linkedStored := "id,weekly_id,date,close\n7,1,1/12/2017,1\n8,1,1/13/2017,2\n"
+	testCases := []struct {
+		name          string
+		td            *TickerData
+		stored        string
+		expectedValue string
+		errorMsg      string
+	}{
+		{"'Ids continue the stored ids'", &partial, "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n", "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", ""},
+		{"'Ids continue without overlap'", &newOnly, "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n", "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", ""},
+		{"'Linked ids continue the stored ids'", &linked, linkedStored, linkedStored + "9,2,1/16/2017,3\n", ""},
+		{"'Linked ids continue without overlap'", &linkedNewOnly, linkedStored, linkedStored + "9,2,1/16/2017,3\n", ""},
+		{"'Stored bar differs'", &changed, "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n", "", "Append Error: The bar of 1/4/2017 has close 99 but 2 is stored."},
+		{"'Bar is not stored'", &daily, "id,date,close\n0,1/3/2017,1\n1,1/5/2017,3\n", "", "Append Error: The bar of 1/4/2017 is not stored."},
+		{"'Empty file'", &daily, "", "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", ""},
+		{"'CRLF line endings'", &daily, "id,date,close\r\n0,1/3/2017,1\r\n1,1/4/2017,2\r\n", "id,date,close\r\n0,1/3/2017,1\r\n1,1/4/2017,2\r\n2,1/5/2017,3\r\n", ""},
+		{"'No trailing newline'", &daily, "id,date,close\n0,1/3/2017,1", "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", ""},
+		{"'No header'", &daily, "0,1/3/2017,1\n", "0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", ""},
+		{"'Up to date'", &daily, "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", "id,date,close\n0,1/3/2017,1\n1,1/4/2017,2\n2,1/5/2017,3\n", ""},
+		{"'Incomplete bar is rewritten'", &weekly, "id,date,close,bar_count,incomplete\n0,1/2/2017,1,5,false\n1,1/9/2017,2,2,true\n",
+			"id,date,close,bar_count,incomplete\n0,1/2/2017,1,5,false\n1,1/9/2017,2.5,5,false\n2,1/16/2017,3,1,true\n", ""},
+		{"'Header does not match'", &daily, "id,date,open\n0,1/3/2017,1\n", "", "Append Error: The stored header"},
+		{"'Duplicate dates'", &duplicates, "id,date,close\n0,1/3/2017,1\n", "", "Append Error: The dates to append"},
+	}
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	resultingFile := outputPath + "testticker-daily.csv"
+	for _, tc := range testCases {
+		ioutil.WriteFile(resultingFile, []byte(tc.stored), 0644)
+		err := csvWriter.writeTickerData("testticker", tc.td, &WriteConfig{TimeFrame: "daily", Append: true})
+		result, _ := ioutil.ReadFile(resultingFile)
+		if tc.errorMsg != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tc.errorMsg) || string(result) != tc.stored {
+				t.Log("Test_writeTickerDataAppendsIncrementally test case ", tc.name, " should fail with: ", tc.errorMsg, " Error was: ", err)
+				t.Fail()
+			}
+			continue
+		}
+		if err != nil || string(result) != tc.expectedValue {
+			t.Log("Test_writeTickerDataAppendsIncrementally test case ", tc.name, " failed. Result was: ", string(result), " but should be: ", tc.expectedValue, " Error: ", err)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package marketdata
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+type EventRule func(td *TickerData, index int) bool
+
+func GenerateEventData(td *TickerData, rule EventRule) EventData {
+	var eventData EventData
+	eventData.Date = make(map[time.Time]bool)
+	l := len(td.Date)
+	for i := 0; i < l; i++ {
+		if rule(td, i) {
+			eventData.Date[td.Date[i]] = true
+		}
+	}
+	return eventData
+}
+
+func GenerateAndWriteEventData(dataWriter DataWriter, td *TickerData, rule EventRule, event *Event) (EventData, error) {
+	eventData := GenerateEventData(td, rule)
+	err := WriteEventData(dataWriter, event, &eventData)
+	return eventData, err
+}
+
+func WriteEventData(dataWriter DataWriter, event *Event, eventData *EventData) error {
+	if event.Name == "" {
+		return errors.New("Event name must not be empty.")
+	}
+	return dataWriter.writeEventData(event, eventData)
+}
+
+func NewHighRule(lookback int) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < lookback {
+			return false
+		}
+		for i := index - lookback; i < index; i++ {
+			if td.High[i] >= td.High[index] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func NewLowRule(lookback int) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < lookback {
+			return false
+		}
+		for i := index - lookback; i < index; i++ {
+			if td.Low[i] <= td.Low[index] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func GapUpRule(minPercent float64) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < 1 || td.Close[index-1] == 0 {
+			return false
+		}
+		return (td.Open[index]-td.Close[index-1])/td.Close[index-1]*100 >= minPercent
+	}
+}
+
+func GapDownRule(minPercent float64) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < 1 || td.Close[index-1] == 0 {
+			return false
+		}
+		return (td.Close[index-1]-td.Open[index])/td.Close[index-1]*100 >= minPercent
+	}
+}
+
+func VolumeSpikeRule(lookback int, multiple float64) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < lookback || lookback < 1 {
+			return false
+		}
+		var total int64
+		for i := index - lookback; i < index; i++ {
+			total = total + td.Volume[i]
+		}
+		average := float64(total) / float64(lookback)
+		return average > 0 && float64(td.Volume[index]) >= average*multiple
+	}
+}
+
+func MovingAverageCrossAboveRule(fastPeriod int, slowPeriod int) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < 1 {
+			return false
+		}
+		prevDiff := closeAverage(td, index-1, fastPeriod) - closeAverage(td, index-1, slowPeriod)
+		curDiff := closeAverage(td, index, fastPeriod) - closeAverage(td, index, slowPeriod)
+		return prevDiff <= 0 && curDiff > 0
+	}
+}
+
+func MovingAverageCrossBelowRule(fastPeriod int, slowPeriod int) EventRule {
+	return func(td *TickerData, index int) bool {
+		if index < 1 {
+			return false
+		}
+		prevDiff := closeAverage(td, index-1, fastPeriod) - closeAverage(td, index-1, slowPeriod)
+		curDiff := closeAverage(td, index, fastPeriod) - closeAverage(td, index, slowPeriod)
+		return prevDiff >= 0 && curDiff < 0
+	}
+}
+
+func AllRules(rules ...EventRule) EventRule {
+	return func(td *TickerData, index int) bool {
+		for _, rule := range rules {
+			if !rule(td, index) {
+				return false
+			}
+		}
+		return len(rules) > 0
+	}
+}
+
+func AnyRule(rules ...EventRule) EventRule {
+	return func(td *TickerData, index int) bool {
+		for _, rule := range rules {
+			if rule(td, index) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func closeAverage(td *TickerData, index int, period int) float64 {
+	if period < 1 || index < period-1 {
+		return math.NaN()
+	}
+	total := 0.0
+	for i := index - period + 1; i <= index; i++ {
+		total = total + td.Close[i]
+	}
+	return total / float64(period)
+}
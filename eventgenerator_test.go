@@ -0,0 +1,77 @@
+package marketdata
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerateEventData(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rule          EventRule
+		expectedDates []string
+	}{
+		{"'New 5 day high'", NewHighRule(5), []string{"12/7/2016", "12/8/2016", "12/9/2016", "12/12/2016", "12/13/2016", "12/27/2016"}},
+		{"'New 5 day low'", NewLowRule(5), []string{"12/16/2016"}},
+		{"'Gap up of at least 0.4 percent'", GapUpRule(0.4), []string{"12/5/2016"}},
+		{"'Gap down of at least 0.3 percent'", GapDownRule(0.3), []string{"12/16/2016"}},
+		{"'Volume spike of 1.35 times the 5 day average'", VolumeSpikeRule(5, 1.35), []string{"12/7/2016", "12/14/2016", "12/16/2016"}},
+		{"'All rules'", AllRules(NewHighRule(5), VolumeSpikeRule(5, 1.35)), []string{"12/7/2016"}},
+		{"'Any rule'", AnyRule(GapUpRule(0.4), GapDownRule(0.3)), []string{"12/5/2016", "12/16/2016"}},
+	}
+	td := getExpectedDailyData()
+	for _, tc := range testCases {
+		result := GenerateEventData(&td, tc.rule)
+		expectedValue := make(map[time.Time]bool)
+		for _, date := range createDates(tc.expectedDates, "1/2/2006") {
+			expectedValue[date] = true
+		}
+		if !reflect.DeepEqual(result.Date, expectedValue) {
+			t.Log("TestGenerateEventData test case ", tc.name, " failed to generate EventData. Result was: ", result.Date, " but should be: ", expectedValue)
+			t.Fail()
+		}
+	}
+}
+
+func TestMovingAverageCrossRules(t *testing.T) {
+	var td TickerData
+	td.Date = createDates([]string{"1/2/2017", "1/3/2017", "1/4/2017", "1/5/2017", "1/6/2017", "1/9/2017"}, "1/2/2006")
+	td.Close = []float64{10, 9, 8, 9, 11, 7}
+	above := GenerateEventData(&td, MovingAverageCrossAboveRule(1, 3))
+	below := GenerateEventData(&td, MovingAverageCrossBelowRule(1, 3))
+	expectedAbove := map[time.Time]bool{td.Date[3]: true}
+	expectedBelow := map[time.Time]bool{td.Date[5]: true}
+	if !reflect.DeepEqual(above.Date, expectedAbove) || !reflect.DeepEqual(below.Date, expectedBelow) {
+		t.Log("TestMovingAverageCrossRules failed. Result was: ", above.Date, below.Date, " but should be: ", expectedAbove, expectedBelow)
+		t.Fail()
+	}
+}
+
+func TestGenerateAndWriteEventData(t *testing.T) {
+	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "event" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{eventname}.csv", DateFormat: "1/2/2006"}
+	td := getExpectedDailyData()
+	event := Event{"testgapup"}
+	_, err := GenerateAndWriteEventData(csvWriter, &td, AnyRule(GapUpRule(0.4), VolumeSpikeRule(5, 1.35)), &event)
+	if err != nil {
+		t.Log("Failed to write EventData. Error is: ", err)
+		t.Fail()
+	}
+	resultingFile := outputPath + "testgapup.csv"
+	result, _ := ioutil.ReadFile(resultingFile)
+	expectedValue := "Date\n12/5/2016\n12/7/2016\n12/14/2016\n12/16/2016\n"
+	if string(result) != expectedValue {
+		t.Log("Failed to write EventData. Result was: ", string(result), " but should be: ", expectedValue)
+		t.Fail()
+	}
+	csvReader := CsvReader{DataPath: outputPath, FileNamePattern: "{eventname}.csv", DateFormat: "1/2/2006"}
+	readBack, err := ReadEventData(csvReader, &event)
+	if err != nil || len(readBack.Date) != 4 {
+		t.Log("Failed to read back written EventData. Result was: ", readBack.Date, " error: ", err)
+		t.Fail()
+	}
+	os.Remove(resultingFile)
+}
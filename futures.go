@@ -0,0 +1,190 @@
+package marketdata
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+type RollMethod string
+
+const (
+	DAYS_BEFORE_EXPIRY      RollMethod = "DAYS_BEFORE_EXPIRY"
+	VOLUME_CROSSOVER        RollMethod = "VOLUME_CROSSOVER"
+	OPEN_INTEREST_CROSSOVER RollMethod = "OPEN_INTEREST_CROSSOVER"
+)
+
+type AdjustmentMethod string
+
+const (
+	DIFFERENCE_ADJUSTMENT AdjustmentMethod = "DIFFERENCE"
+	RATIO_ADJUSTMENT      AdjustmentMethod = "RATIO"
+	NO_ADJUSTMENT         AdjustmentMethod = "NONE"
+)
+
+type FuturesContract struct {
+	Symbol string
+	Expiry time.Time
+}
+
+type RollRule struct {
+	Method           RollMethod
+	DaysBeforeExpiry int
+}
+
+// Roll records the first date on which the continuous contract uses ToSymbol and the
+// price difference and ratio between the two contracts on that date.
+type Roll struct {
+	Date       time.Time
+	FromSymbol string
+	ToSymbol   string
+	Difference float64
+	Ratio      float64
+}
+
+func ReadContinuousContract(dataReader DataReader, contracts []FuturesContract, readConfig *ReadConfig, rule *RollRule, adjustment AdjustmentMethod) (TickerData, []Roll, error) {
+	contractData := make([]TickerData, len(contracts))
+	for i, contract := range contracts {
+		td, err := dataReader.readTickerData(contract.Symbol, readConfig)
+		if err != nil {
+			return TickerData{}, nil, err
+		}
+		contractData[i] = createSortedTickerData(&td, []string{})
+	}
+	return BuildContinuousContract(contracts, contractData, rule, adjustment)
+}
+
+// BuildContinuousContract stitches the contracts in order of expiry into one series.
+// Bars before each roll are back-adjusted so the series has no gap at the roll.
+func BuildContinuousContract(contracts []FuturesContract, contractData []TickerData, rule *RollRule, adjustment AdjustmentMethod) (TickerData, []Roll, error) {
+	var td TickerData
+	if len(contracts) == 0 || len(contracts) != len(contractData) {
+		return td, nil, errors.New("Each futures contract requires ticker data.")
+	}
+	if rule == nil {
+		return td, nil, errors.New("A roll rule is required to build a continuous contract.")
+	}
+	order := make([]int, len(contracts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return contracts[order[i]].Expiry.Before(contracts[order[j]].Expiry) })
+	rolls := []Roll{}
+	begin := make([]int, len(order))
+	end := make([]int, len(order))
+	end[len(order)-1] = len(contractData[order[len(order)-1]].Date)
+	for x := 0; x < len(order)-1; x++ {
+		cur := &contractData[order[x]]
+		next := &contractData[order[x+1]]
+		curIndex, nextIndex := findRollIndex(cur, next, contracts[order[x]].Expiry, rule, begin[x])
+		if curIndex == -1 {
+			return td, nil, errors.New("Contracts " + contracts[order[x]].Symbol + " and " + contracts[order[x+1]].Symbol + " have no common dates to roll on.")
+		}
+		if !validRollPrice(cur.Close[curIndex]) || !validRollPrice(next.Close[nextIndex]) {
+			return td, nil, errors.New("Contracts " + contracts[order[x]].Symbol + " and " + contracts[order[x+1]].Symbol + " have no valid close to roll on " + next.Date[nextIndex].String() + ".")
+		}
+		end[x] = curIndex
+		begin[x+1] = nextIndex
+		rolls = append(rolls, Roll{next.Date[nextIndex], contracts[order[x]].Symbol, contracts[order[x+1]].Symbol,
+			next.Close[nextIndex] - cur.Close[curIndex], next.Close[nextIndex] / cur.Close[curIndex]})
+	}
+	size := 0
+	for x := range order {
+		size = size + end[x] - begin[x]
+	}
+	first := &contractData[order[0]]
+	fields := map[string]int{"id": 0, "date": 1, "open": 2, "high": 3, "low": 4, "close": 5, "volume": 6}
+	if first.OpenInterest != nil {
+		fields["open_interest"] = 7
+	}
+	td.initialize(fields, size)
+	td.Precision = first.Precision
+	td.Calendar = first.Calendar
+	index := 0
+	rollIndex := make([]int, len(rolls))
+	for x := range order {
+		if x > 0 {
+			rollIndex[x-1] = index
+		}
+		for i := begin[x]; i < end[x]; i++ {
+			td.addItem(&contractData[order[x]], index, i, index)
+			index++
+		}
+	}
+	if adjustment != NO_ADJUSTMENT {
+		td.adjustForRolls(rolls, rollIndex, adjustment)
+	}
+	return td, rolls, nil
+}
+
+// validRollPrice reports whether price can be used for the difference and ratio of a
+// roll, which would otherwise spread Inf or NaN through all earlier bars.
+func validRollPrice(price float64) bool {
+	return price != 0 && !math.IsNaN(price) && !math.IsInf(price, 0)
+}
+
+// findRollIndex returns the index of the roll date in cur and next. The roll date is
+// the first date present in both contracts on which the roll rule is met, or the last
+// common date if the rule is never met.
+func findRollIndex(cur *TickerData, next *TickerData, expiry time.Time, rule *RollRule, begin int) (int, int) {
+	rollDate := expiry.AddDate(0, 0, -rule.DaysBeforeExpiry)
+	lastCur, lastNext := -1, -1
+	j := 0
+	for i := begin; i < len(cur.Date); i++ {
+		for j < len(next.Date) && next.Date[j].Before(cur.Date[i]) {
+			j++
+		}
+		if j == len(next.Date) {
+			break
+		}
+		if !next.Date[j].Equal(cur.Date[i]) {
+			continue
+		}
+		lastCur, lastNext = i, j
+		switch rule.Method {
+		case DAYS_BEFORE_EXPIRY:
+			if !cur.Date[i].Before(rollDate) {
+				return i, j
+			}
+		case VOLUME_CROSSOVER:
+			if next.Volume[j] > cur.Volume[i] {
+				return i, j
+			}
+		case OPEN_INTEREST_CROSSOVER:
+			if next.OpenInterest != nil && cur.OpenInterest != nil && next.OpenInterest[j] > cur.OpenInterest[i] {
+				return i, j
+			}
+		}
+	}
+	return lastCur, lastNext
+}
+
+func (td *TickerData) adjustForRolls(rolls []Roll, rollIndex []int, adjustment AdjustmentMethod) {
+	dp := td.pricePrecision()
+	difference := 0.0
+	ratio := 1.0
+	x := len(rolls) - 1
+	for i := len(td.Date) - 1; i >= 0; i-- {
+		for x >= 0 && i < rollIndex[x] {
+			difference = difference + rolls[x].Difference
+			ratio = ratio * rolls[x].Ratio
+			x--
+		}
+		if adjustment == RATIO_ADJUSTMENT && ratio != 1 {
+			td.adjustTickerDataItem(i, ratio, 1, dp)
+		} else if adjustment == DIFFERENCE_ADJUSTMENT && difference != 0 {
+			td.shiftTickerDataItem(i, difference, dp)
+		}
+	}
+}
+
+func (td *TickerData) shiftTickerDataItem(index int, difference float64, dp int) {
+	td.Open[index] = roundPlus(td.Open[index]+difference, dp)
+	td.High[index] = roundPlus(td.High[index]+difference, dp)
+	td.Low[index] = roundPlus(td.Low[index]+difference, dp)
+	td.Close[index] = roundPlus(td.Close[index]+difference, dp)
+	if td.Vwap != nil {
+		td.Vwap[index] = roundPlus(td.Vwap[index]+difference, dp)
+	}
+}
@@ -0,0 +1,73 @@
+package marketdata
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadContinuousContract(t *testing.T) {
+	testCases := []struct {
+		name               string
+		rule               RollRule
+		adjustment         AdjustmentMethod
+		expectedRollDate   string
+		expectedDifference float64
+		expectedClose      []float64
+	}{
+		{"'Volume crossover with difference adjustment'", RollRule{Method: VOLUME_CROSSOVER}, DIFFERENCE_ADJUSTMENT, "3/10/2017", -8.25,
+			[]float64{2354, 2356.25, 2360.5, 2363.25, 2357.75, 2366}},
+		{"'Open interest crossover with ratio adjustment'", RollRule{Method: OPEN_INTEREST_CROSSOVER}, RATIO_ADJUSTMENT, "3/13/2017", -6.75,
+			[]float64{2355.52, 2357.77, 2362, 2363.25, 2357.75, 2366}},
+		{"'Five days before expiry without adjustment'", RollRule{Method: DAYS_BEFORE_EXPIRY, DaysBeforeExpiry: 5}, NO_ADJUSTMENT, "3/13/2017", -6.75,
+			[]float64{2362.25, 2364.5, 2368.75, 2363.25, 2357.75, 2366}},
+	}
+	var csvReader CsvReader
+	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	csvReader.FileNamePattern = "{ticker}-{timeframe}.csv"
+	csvReader.DateFormat = "1/2/2006"
+	march, _ := time.Parse(csvReader.DateFormat, "3/17/2017")
+	june, _ := time.Parse(csvReader.DateFormat, "6/16/2017")
+	contracts := []FuturesContract{{"esm17", june}, {"esh17", march}}
+	for _, tc := range testCases {
+		td, rolls, err := ReadContinuousContract(csvReader, contracts, &ReadConfig{TimeFrame: "daily"}, &tc.rule, tc.adjustment)
+		expectedRollDate, _ := time.Parse(csvReader.DateFormat, tc.expectedRollDate)
+		if err != nil || len(rolls) != 1 || !rolls[0].Date.Equal(expectedRollDate) || rolls[0].FromSymbol != "esh17" ||
+			rolls[0].Difference != tc.expectedDifference || !reflect.DeepEqual(td.Close, tc.expectedClose) {
+			t.Log("TestReadContinuousContract test case ", tc.name, " failed. Result was: ", td.Close, " rolls: ", rolls, " but should be: ", tc.expectedClose, " Error: ", err)
+			t.Fail()
+		}
+		if td.OpenInterest == nil || td.Id[len(td.Id)-1] != 5 {
+			t.Log("TestReadContinuousContract test case ", tc.name, " failed to keep ids and open interest. Result was: ", td)
+			t.Fail()
+		}
+	}
+}
+
+func TestBuildContinuousContractRequiresData(t *testing.T) {
+	var cur, next TickerData
+	cur.Date = createDates([]string{"3/1/2017", "3/2/2017"}, "1/2/2006")
+	cur.Close = []float64{0, 0}
+	cur.Volume = []int64{200, 100}
+	next.Date = cur.Date
+	next.Close = []float64{2300, 2301}
+	next.Volume = []int64{100, 200}
+	contracts := []FuturesContract{{Symbol: "esh17"}, {Symbol: "esm17"}}
+	testCases := []struct {
+		name         string
+		contracts    []FuturesContract
+		contractData []TickerData
+		rule         *RollRule
+	}{
+		{"'No ticker data'", contracts[:1], []TickerData{}, &RollRule{Method: VOLUME_CROSSOVER}},
+		{"'No roll rule'", contracts, []TickerData{cur, next}, nil},
+		{"'Zero close on the roll date'", contracts, []TickerData{cur, next}, &RollRule{Method: VOLUME_CROSSOVER}},
+	}
+	for _, tc := range testCases {
+		if _, _, err := BuildContinuousContract(tc.contracts, tc.contractData, tc.rule, RATIO_ADJUSTMENT); err == nil {
+			t.Log("TestBuildContinuousContractRequiresData test case ", tc.name, " should fail.")
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package marketdata
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HttpReader reads the same files as CsvReader from a web server. UrlTemplate is a URL
+// with the placeholders of FileNamePattern, e.g. https://host/data/{ticker}-{timeframe}.csv
+// or https://host/events/{eventname}.csv.
+//
+// Requests failing with a network error, 429 or 5xx are retried MaxRetries times,
+// waiting Backoff before the first retry and twice as long before each further one.
+// Consecutive requests are at least MinInterval apart. When CacheDir is set, responses
+// are stored there and reused until they are older than CacheMaxAge, or forever when
+// CacheMaxAge is 0. Failing to write the cache does not fail a read. Use a pointer,
+// as the rate limit is shared by all copies of it. The price precision is resolved
+// like the one of CsvReader.
+type HttpReader struct {
+	UrlTemplate     string
+	DateFormat      string
+	PricePrecision  *int
+	SymbolPrecision map[string]int
+	DetectPrecision bool
+	Location        *time.Location
+	Client          *http.Client
+	MaxRetries      int
+	Backoff         time.Duration
+	MinInterval     time.Duration
+	CacheDir        string
+	CacheMaxAge     time.Duration
+	mutex           sync.Mutex
+	lastRequest     time.Time
+}
+
+func (httpReader *HttpReader) readTickerData(symbol string, tickerConfig *ReadConfig) (TickerData, error) {
+	body, err := httpReader.fetch(getTickerDataFileName(httpReader.UrlTemplate, url.PathEscape(symbol), tickerConfig.TimeFrame))
+	if err != nil {
+		return TickerData{}, err
+	}
+	tickerData, err := parseTickerData(bytes.NewReader(body), tickerConfig, httpReader.DateFormat, httpReader.Location)
+	if err != nil {
+		return tickerData, err
+	}
+	tickerData.Precision = resolvePricePrecision(&tickerData, symbol, httpReader.PricePrecision, httpReader.SymbolPrecision, httpReader.DetectPrecision)
+	return tickerData, nil
+}
+
+func (httpReader *HttpReader) readEventData(event *Event) (EventData, error) {
+	body, err := httpReader.fetch(getEventDataFileName(httpReader.UrlTemplate, url.PathEscape(event.Name)))
+	if err != nil {
+		return EventData{Date: make(map[time.Time]bool)}, err
+	}
+	return parseEventData(bytes.NewReader(body), httpReader.DateFormat, httpReader.Location)
+}
+
+func (httpReader *HttpReader) readDividendData(symbol string, source DataSource) (TickerDividendData, error) {
+	body, err := httpReader.fetch(getFileName(httpReader.UrlTemplate, "{ticker}", url.PathEscape(symbol)))
+	if err != nil {
+		return TickerDividendData{}, err
+	}
+	return parseDividendData(bytes.NewReader(body), source, httpReader.DateFormat, httpReader.Location)
+}
+
+func (httpReader *HttpReader) readSplitData(symbol string, source DataSource) (TickerSplitData, error) {
+	body, err := httpReader.fetch(getFileName(httpReader.UrlTemplate, "{ticker}", url.PathEscape(symbol)))
+	if err != nil {
+		return TickerSplitData{}, err
+	}
+	return parseSplitData(bytes.NewReader(body), source, httpReader.DateFormat, httpReader.Location)
+}
+
+func (httpReader *HttpReader) readTickData(symbol string, tickConfig *ReadConfig) (TickData, error) {
+	var tickData TickData
+	err := httpReader.readColumnarData(&tickData, symbol, tickConfig, []string{"price", "size"})
+	tickData.Precision = resolvePricePrecision(&TickerData{Close: tickData.Price}, symbol, httpReader.PricePrecision, httpReader.SymbolPrecision, httpReader.DetectPrecision)
+	return tickData, err
+}
+
+func (httpReader *HttpReader) readQuoteData(symbol string, quoteConfig *ReadConfig) (QuoteData, error) {
+	var quoteData QuoteData
+	err := httpReader.readColumnarData(&quoteData, symbol, quoteConfig, []string{"bid", "ask"})
+	return quoteData, err
+}
+
+func (httpReader *HttpReader) readColumnarData(data Data, symbol string, config *ReadConfig, requiredFields []string) error {
+	body, err := httpReader.fetch(getTickerDataFileName(httpReader.UrlTemplate, url.PathEscape(symbol), config.TimeFrame))
+	if err != nil {
+		return err
+	}
+	return parseColumnarData(data, bytes.NewReader(body), config, requiredFields, httpReader.DateFormat, httpReader.Location)
+}
+
+func (httpReader *HttpReader) getDateFormat() string {
+	return httpReader.DateFormat
+}
+
+func (httpReader *HttpReader) getLocation() *time.Location {
+	return httpReader.Location
+}
+
+func (httpReader *HttpReader) fetch(fileUrl string) ([]byte, error) {
+	cacheFile := httpReader.cacheFileName(fileUrl)
+	if cacheFile != "" {
+		if info, err := os.Stat(cacheFile); err == nil && (httpReader.CacheMaxAge == 0 || time.Since(info.ModTime()) < httpReader.CacheMaxAge) {
+			if body, err := ioutil.ReadFile(cacheFile); err == nil {
+				return body, nil
+			}
+		}
+	}
+	var body []byte
+	var err error
+	retry := true
+	wait := httpReader.Backoff
+	for attempt := 0; retry && attempt <= httpReader.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		body, retry, err = httpReader.get(fileUrl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cacheFile != "" {
+		httpReader.saveToCache(cacheFile, body)
+	}
+	return body, nil
+}
+
+// saveToCache replaces cacheFile atomically, so other readers never see a partly
+// written file. The cache is best-effort and the body is skipped when it cannot be
+// written or another reader is writing it.
+func (httpReader *HttpReader) saveToCache(cacheFile string, body []byte) {
+	if err := os.MkdirAll(httpReader.CacheDir, 0755); err != nil {
+		return
+	}
+	af, err := createAtomicFile(cacheFile, 0)
+	if err != nil {
+		return
+	}
+	defer af.Close()
+	if _, err = af.Write(body); err == nil {
+		af.commit()
+	}
+}
+
+// get requests fileUrl once and reports whether a failed request may be retried.
+func (httpReader *HttpReader) get(fileUrl string) ([]byte, bool, error) {
+	httpReader.waitForRateLimit()
+	client := httpReader.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Get(fileUrl)
+	if err != nil {
+		return nil, true, errors.New("Http Error: " + err.Error())
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	switch {
+	case err != nil:
+		return nil, true, errors.New("Http Error: " + err.Error())
+	case response.StatusCode == http.StatusNotFound:
+		return nil, false, notFoundError{fmt.Sprintf("File Open Error: %s returned %s", fileUrl, response.Status)}
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return nil, true, fmt.Errorf("Http Error: %s returned %s", fileUrl, response.Status)
+	case response.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("Http Error: %s returned %s", fileUrl, response.Status)
+	}
+	return body, false, nil
+}
+
+func (httpReader *HttpReader) waitForRateLimit() {
+	httpReader.mutex.Lock()
+	defer httpReader.mutex.Unlock()
+	if wait := httpReader.MinInterval - time.Since(httpReader.lastRequest); wait > 0 {
+		time.Sleep(wait)
+	}
+	httpReader.lastRequest = time.Now()
+}
+
+func (httpReader *HttpReader) cacheFileName(fileUrl string) string {
+	if httpReader.CacheDir == "" {
+		return ""
+	}
+	hash := sha1.Sum([]byte(fileUrl))
+	name := hex.EncodeToString(hash[:])
+	if ext := filepath.Ext(strings.SplitN(fileUrl, "?", 2)[0]); len(ext) <= 5 {
+		name = name + ext
+	}
+	return filepath.Join(httpReader.CacheDir, name)
+}
@@ -0,0 +1,130 @@
+package marketdata
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// getTestFileServer serves testdata and fails the first failures requests with 503.
+func getTestFileServer(failures int32, requests *int32) *httptest.Server {
+	files := http.FileServer(http.Dir("." + string(os.PathSeparator) + "testdata"))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) <= failures {
+			http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+}
+
+func TestHttpReader(t *testing.T) {
+	var requests int32
+	server := getTestFileServer(0, &requests)
+	defer server.Close()
+	tickerReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-{timeframe}.csv", DateFormat: "1/2/2006", PricePrecision: NewPrecision(3)}
+	data, err := ReadTickerData(tickerReader, &TickerForRead{Symbol: "someticker", Config: []ReadConfig{{TimeFrame: "daily"}}})
+	if err != nil || len(data["daily"].Date) != 3 || data["daily"].Close[2] != 138.3 || data["daily"].pricePrecision() != 3 {
+		t.Log("TestHttpReader failed to read ticker data. Result was: ", data["daily"], " Error: ", err)
+		t.Fail()
+	}
+	splitReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-splitdata.csv", DateFormat: "20060102"}
+	tsd, err := ReadSplitData(splitReader, "someticker", OTHER)
+	if err != nil || len(tsd.Date) != 2 {
+		t.Log("TestHttpReader failed to read split data. Result was: ", tsd, " Error: ", err)
+		t.Fail()
+	}
+	dividendReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-yahoosplitdividend.csv", DateFormat: "20060102"}
+	tdd, err := ReadDividendData(dividendReader, "someticker", YAHOO)
+	if err != nil || len(tdd.Date) != 4 {
+		t.Log("TestHttpReader failed to read dividend data. Result was: ", tdd, " Error: ", err)
+		t.Fail()
+	}
+	eventReader := &HttpReader{UrlTemplate: server.URL + "/event/{eventname}.csv", DateFormat: "1/2/2006"}
+	eventData, err := ReadEventData(eventReader, &Event{"testevent"})
+	if err != nil || len(eventData.Date) == 0 {
+		t.Log("TestHttpReader failed to read event data. Error: ", err)
+		t.Fail()
+	}
+	_, err = ReadTickerData(tickerReader, &TickerForRead{Symbol: "missing", Config: []ReadConfig{{TimeFrame: "daily"}}})
+	if err == nil || !strings.HasPrefix(err.Error(), "File Open Error") {
+		t.Log("TestHttpReader should fail with a File Open Error for a missing file. Error was: ", err)
+		t.Fail()
+	}
+}
+
+func TestHttpReaderRetries(t *testing.T) {
+	var requests int32
+	server := getTestFileServer(2, &requests)
+	defer server.Close()
+	httpReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-{timeframe}.csv", DateFormat: "1/2/2006", MaxRetries: 2, Backoff: time.Millisecond}
+	_, err := httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	if err != nil || requests != 3 {
+		t.Log("TestHttpReaderRetries failed. Requests were: ", requests, " but should be: 3 Error: ", err)
+		t.Fail()
+	}
+	requests = 0
+	httpReader.MaxRetries = 1
+	_, err = httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	if err == nil || !strings.Contains(err.Error(), "503") || requests != 2 {
+		t.Log("TestHttpReaderRetries should give up after 2 requests. Requests were: ", requests, " Error: ", err)
+		t.Fail()
+	}
+}
+
+func TestHttpReaderRateLimit(t *testing.T) {
+	var requests int32
+	server := getTestFileServer(0, &requests)
+	defer server.Close()
+	httpReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-{timeframe}.csv", DateFormat: "1/2/2006", MinInterval: 20 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Log("TestHttpReaderRateLimit failed. 3 requests took: ", elapsed, " but should take at least 40ms")
+		t.Fail()
+	}
+}
+
+func TestHttpReaderCache(t *testing.T) {
+	var requests int32
+	server := getTestFileServer(0, &requests)
+	defer server.Close()
+	cacheDir := t.TempDir()
+	httpReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-{timeframe}.csv", DateFormat: "1/2/2006", CacheDir: cacheDir}
+	first, err := httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	second, _ := httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	files, _ := ioutil.ReadDir(cacheDir)
+	if err != nil || requests != 1 || len(files) != 1 || len(second.Date) != len(first.Date) {
+		t.Log("TestHttpReaderCache failed. Requests were: ", requests, " but should be: 1 Error: ", err)
+		t.Fail()
+	}
+	httpReader.CacheMaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	if requests != 2 {
+		t.Log("TestHttpReaderCache should refresh an expired file. Requests were: ", requests, " but should be: 2")
+		t.Fail()
+	}
+}
+
+func TestHttpReaderIgnoresCacheErrors(t *testing.T) {
+	var requests int32
+	server := getTestFileServer(0, &requests)
+	defer server.Close()
+	// The cache directory cannot be created below a file.
+	cacheFile := t.TempDir() + string(os.PathSeparator) + "cache"
+	ioutil.WriteFile(cacheFile, []byte{}, 0644)
+	httpReader := &HttpReader{UrlTemplate: server.URL + "/ticker/{ticker}-{timeframe}.csv", DateFormat: "1/2/2006", CacheDir: cacheFile + string(os.PathSeparator) + "http"}
+	td, err := httpReader.readTickerData("someticker", &ReadConfig{TimeFrame: "daily"})
+	if err != nil || len(td.Date) != 3 {
+		t.Log("TestHttpReaderIgnoresCacheErrors should return the downloaded data. Result was: ", td.Date, " Error: ", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,273 @@
+// Package indicators computes technical indicators over TickerData columns. All
+// indicators return a slice aligned with their input where the values of the warm-up
+// period are NaN. Leading NaN values of the input are skipped, so indicators can be
+// chained, e.g. an EMA over an RSI.
+package indicators
+
+import (
+	"math"
+
+	"github.com/fasterbull/marketdata"
+)
+
+func SMA(values []float64, period int) []float64 {
+	result := nanSlice(len(values))
+	begin := firstValid(values)
+	if period < 1 || begin < 0 {
+		return result
+	}
+	sum := 0.0
+	for i := begin; i < len(values); i++ {
+		sum = sum + values[i]
+		if i-begin >= period {
+			sum = sum - values[i-period]
+		}
+		if i-begin >= period-1 {
+			result[i] = sum / float64(period)
+		}
+	}
+	return result
+}
+
+// EMA is seeded with the SMA of the first period values.
+func EMA(values []float64, period int) []float64 {
+	result := SMA(values, period)
+	begin := firstValid(result)
+	if begin < 0 {
+		return result
+	}
+	alpha := 2 / float64(period+1)
+	for i := begin + 1; i < len(values); i++ {
+		result[i] = alpha*values[i] + (1-alpha)*result[i-1]
+	}
+	return result
+}
+
+func WMA(values []float64, period int) []float64 {
+	result := nanSlice(len(values))
+	begin := firstValid(values)
+	if period < 1 || begin < 0 {
+		return result
+	}
+	weights := float64(period*(period+1)) / 2
+	for i := begin + period - 1; i < len(values); i++ {
+		sum := 0.0
+		for j := 0; j < period; j++ {
+			sum = sum + values[i-j]*float64(period-j)
+		}
+		result[i] = sum / weights
+	}
+	return result
+}
+
+// RSI uses Wilder's smoothing of the average gain and loss.
+func RSI(values []float64, period int) []float64 {
+	result := nanSlice(len(values))
+	begin := firstValid(values)
+	if period < 1 || begin < 0 || len(values)-begin <= period {
+		return result
+	}
+	gain, loss := 0.0, 0.0
+	for i := begin + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		up, down := math.Max(change, 0), math.Max(-change, 0)
+		if i-begin <= period {
+			gain = gain + up/float64(period)
+			loss = loss + down/float64(period)
+			if i-begin < period {
+				continue
+			}
+		} else {
+			gain = (gain*float64(period-1) + up) / float64(period)
+			loss = (loss*float64(period-1) + down) / float64(period)
+		}
+		if loss == 0 {
+			result[i] = 100
+		} else {
+			result[i] = 100 - 100/(1+gain/loss)
+		}
+	}
+	return result
+}
+
+func MACD(values []float64, fastPeriod int, slowPeriod int, signalPeriod int) ([]float64, []float64, []float64) {
+	fast := EMA(values, fastPeriod)
+	slow := EMA(values, slowPeriod)
+	macd := make([]float64, len(values))
+	for i := range values {
+		macd[i] = fast[i] - slow[i]
+	}
+	signal := EMA(macd, signalPeriod)
+	histogram := make([]float64, len(values))
+	for i := range values {
+		histogram[i] = macd[i] - signal[i]
+	}
+	return macd, signal, histogram
+}
+
+// BollingerBands returns the middle, upper and lower band using the population
+// standard deviation over period.
+func BollingerBands(values []float64, period int, multiple float64) ([]float64, []float64, []float64) {
+	middle := SMA(values, period)
+	upper := nanSlice(len(values))
+	lower := nanSlice(len(values))
+	for i := range values {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		variance := 0.0
+		for j := i - period + 1; j <= i; j++ {
+			variance = variance + (values[j]-middle[i])*(values[j]-middle[i])
+		}
+		deviation := math.Sqrt(variance / float64(period))
+		upper[i] = middle[i] + multiple*deviation
+		lower[i] = middle[i] - multiple*deviation
+	}
+	return middle, upper, lower
+}
+
+func TrueRange(td *marketdata.TickerData) []float64 {
+	result := make([]float64, len(td.Close))
+	for i := range td.Close {
+		result[i] = td.High[i] - td.Low[i]
+		if i > 0 {
+			result[i] = math.Max(result[i], math.Max(math.Abs(td.High[i]-td.Close[i-1]), math.Abs(td.Low[i]-td.Close[i-1])))
+		}
+	}
+	return result
+}
+
+func ATR(td *marketdata.TickerData, period int) []float64 {
+	return wilderAverage(TrueRange(td), period)
+}
+
+// ADX returns the average directional index together with the +DI and -DI lines.
+func ADX(td *marketdata.TickerData, period int) ([]float64, []float64, []float64) {
+	l := len(td.Close)
+	plusDI := nanSlice(l)
+	minusDI := nanSlice(l)
+	dx := nanSlice(l)
+	if period < 1 || l <= period {
+		return nanSlice(l), plusDI, minusDI
+	}
+	trueRange := TrueRange(td)
+	plusDM := make([]float64, l)
+	minusDM := make([]float64, l)
+	for i := 1; i < l; i++ {
+		up := td.High[i] - td.High[i-1]
+		down := td.Low[i-1] - td.Low[i]
+		if up > down && up > 0 {
+			plusDM[i] = up
+		}
+		if down > up && down > 0 {
+			minusDM[i] = down
+		}
+	}
+	smoothedTr := wilderAverage(trueRange[1:], period)
+	smoothedPlus := wilderAverage(plusDM[1:], period)
+	smoothedMinus := wilderAverage(minusDM[1:], period)
+	for i := 1; i < l; i++ {
+		if math.IsNaN(smoothedTr[i-1]) || smoothedTr[i-1] == 0 {
+			continue
+		}
+		plusDI[i] = 100 * smoothedPlus[i-1] / smoothedTr[i-1]
+		minusDI[i] = 100 * smoothedMinus[i-1] / smoothedTr[i-1]
+		dx[i] = 0
+		if plusDI[i]+minusDI[i] > 0 {
+			dx[i] = 100 * math.Abs(plusDI[i]-minusDI[i]) / (plusDI[i] + minusDI[i])
+		}
+	}
+	return wilderAverage(dx, period), plusDI, minusDI
+}
+
+// Stochastic returns the %K line over kPeriod and its SMA over dPeriod as %D.
+func Stochastic(td *marketdata.TickerData, kPeriod int, dPeriod int) ([]float64, []float64) {
+	k := nanSlice(len(td.Close))
+	for i := kPeriod - 1; kPeriod > 0 && i < len(td.Close); i++ {
+		highest, lowest := td.High[i], td.Low[i]
+		for j := i - kPeriod + 1; j < i; j++ {
+			highest = math.Max(highest, td.High[j])
+			lowest = math.Min(lowest, td.Low[j])
+		}
+		k[i] = 50
+		if highest > lowest {
+			k[i] = 100 * (td.Close[i] - lowest) / (highest - lowest)
+		}
+	}
+	return k, SMA(k, dPeriod)
+}
+
+func OBV(td *marketdata.TickerData) []float64 {
+	result := make([]float64, len(td.Close))
+	for i := 1; i < len(td.Close); i++ {
+		result[i] = result[i-1]
+		if td.Close[i] > td.Close[i-1] {
+			result[i] = result[i] + float64(td.Volume[i])
+		} else if td.Close[i] < td.Close[i-1] {
+			result[i] = result[i] - float64(td.Volume[i])
+		}
+	}
+	return result
+}
+
+// VWAP returns the volume weighted average price of the session of each bar. Intraday
+// bars with daily ids start a session with every day and other bars form one session
+// over all bars. The vwap column of the bars is used when present and the typical price
+// otherwise.
+func VWAP(td *marketdata.TickerData) []float64 {
+	result := nanSlice(len(td.Close))
+	dailyIds := td.HigherTfIds["daily_id"]
+	notional, volume := 0.0, 0.0
+	for i := range td.Close {
+		if dailyIds != nil && i > 0 && dailyIds[i] != dailyIds[i-1] {
+			notional, volume = 0, 0
+		}
+		price := (td.High[i] + td.Low[i] + td.Close[i]) / 3
+		if td.Vwap != nil {
+			price = td.Vwap[i]
+		}
+		notional = notional + price*float64(td.Volume[i])
+		volume = volume + float64(td.Volume[i])
+		if volume > 0 {
+			result[i] = notional / volume
+		}
+	}
+	return result
+}
+
+// Project maps values computed on higher time frame bars, like the bars created by
+// marketdata.Resample, onto the bars of td using the higher time frame ids of td.
+// Without includeInProgress every bar only sees the last higher time frame bar that
+// was complete at its close.
+func Project(values []float64, td *marketdata.TickerData, higherTimeFrame string, includeInProgress bool) ([]float64, error) {
+	return marketdata.ProjectHigherTimeFrame(td, higherTimeFrame, values, includeInProgress)
+}
+
+func wilderAverage(values []float64, period int) []float64 {
+	result := SMA(values, period)
+	begin := firstValid(result)
+	if begin < 0 {
+		return result
+	}
+	for i := begin + 1; i < len(values); i++ {
+		result[i] = (result[i-1]*float64(period-1) + values[i]) / float64(period)
+	}
+	return result
+}
+
+func firstValid(values []float64) int {
+	for i, value := range values {
+		if !math.IsNaN(value) {
+			return i
+		}
+	}
+	return -1
+}
+
+func nanSlice(size int) []float64 {
+	result := make([]float64, size)
+	for i := range result {
+		result[i] = math.NaN()
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fasterbull/marketdata"
+)
+
+func TestIndicators(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	var td marketdata.TickerData
+	td.High = []float64{11, 12, 13}
+	td.Low = []float64{9, 10, 10}
+	td.Close = []float64{10, 11, 12}
+	td.Volume = []int64{100, 200, 300}
+	macd, signal, _ := MACD(values, 2, 3, 2)
+	middle, upper, lower := BollingerBands([]float64{1, 2, 3}, 3, 2)
+	k, d := Stochastic(&td, 2, 2)
+	testCases := []struct {
+		name           string
+		result         []float64
+		expectedResult []float64
+	}{
+		{"'SMA'", SMA(values, 3), []float64{nan, nan, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"'EMA'", EMA(values, 3), []float64{nan, nan, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"'WMA'", WMA(values[:4], 3), []float64{nan, nan, 14.0 / 6, 20.0 / 6}},
+		{"'RSI'", RSI([]float64{1, 2, 1, 2, 3}, 2), []float64{nan, nan, 50, 75, 87.5}},
+		{"'MACD'", macd[:4], []float64{nan, nan, 0.5, 0.5}},
+		{"'MACD signal'", signal[:4], []float64{nan, nan, nan, 0.5}},
+		{"'Bollinger middle band'", middle, []float64{nan, nan, 2}},
+		{"'Bollinger upper band'", upper, []float64{nan, nan, 2 + 2*math.Sqrt(2.0/3)}},
+		{"'Bollinger lower band'", lower, []float64{nan, nan, 2 - 2*math.Sqrt(2.0/3)}},
+		{"'ATR'", ATR(&td, 2), []float64{nan, 2, 2.5}},
+		{"'Stochastic %K'", k, []float64{nan, 200.0 / 3, 200.0 / 3}},
+		{"'Stochastic %D'", d, []float64{nan, nan, 200.0 / 3}},
+		{"'OBV'", OBV(&td), []float64{0, 200, 500}},
+		{"'VWAP'", VWAP(&td), []float64{10, 3200.0 / 300, 6700.0 / 600}},
+	}
+	for _, tc := range testCases {
+		if !approxEqual(tc.result, tc.expectedResult) {
+			t.Log("TestIndicators test case ", tc.name, " failed. Result was: ", tc.result, " but should be: ", tc.expectedResult)
+			t.Fail()
+		}
+	}
+}
+
+func TestSessionVWAP(t *testing.T) {
+	var td marketdata.TickerData
+	td.High = []float64{10, 12, 20, 22}
+	td.Low = td.High
+	td.Close = td.High
+	td.Volume = []int64{100, 100, 100, 300}
+	td.HigherTfIds = map[string][]int32{"daily_id": {-1, -1, 0, 0}}
+	expectedResult := []float64{10, 11, 20, 21.5}
+	if result := VWAP(&td); !approxEqual(result, expectedResult) {
+		t.Log("TestSessionVWAP failed to start a session each day. Result was: ", result, " but should be: ", expectedResult)
+		t.Fail()
+	}
+}
+
+func TestADX(t *testing.T) {
+	var td marketdata.TickerData
+	for i := 0; i < 10; i++ {
+		td.High = append(td.High, float64(11+i))
+		td.Low = append(td.Low, float64(9+i))
+		td.Close = append(td.Close, float64(10+i))
+	}
+	adx, plusDI, minusDI := ADX(&td, 3)
+	if !math.IsNaN(adx[4]) || adx[5] != 100 || !math.IsNaN(plusDI[2]) || plusDI[3] <= 0 || minusDI[3] != 0 {
+		t.Log("TestADX failed. Result was: ", adx, plusDI, minusDI)
+		t.Fail()
+	}
+}
+
+func TestProject(t *testing.T) {
+	var td marketdata.TickerData
+	td.Date = []time.Time{
+		time.Date(2017, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2017, 1, 6, 0, 0, 0, 0, time.UTC),
+		time.Date(2017, 1, 9, 0, 0, 0, 0, time.UTC), time.Date(2017, 1, 13, 0, 0, 0, 0, time.UTC),
+		time.Date(2017, 1, 16, 0, 0, 0, 0, time.UTC),
+	}
+	td.HigherTfIds = map[string][]int32{"weekly_id": {-1, -1, 0, 0, 1}}
+	result, err := Project([]float64{10, 20, 30}, &td, "weekly", true)
+	if err != nil || !approxEqual(result, []float64{10, 10, 20, 20, 30}) {
+		t.Log("TestProject failed. Result was: ", result, " Error: ", err)
+		t.Fail()
+	}
+	result, err = Project([]float64{10, 20, 30}, &td, "weekly", false)
+	if err != nil || !approxEqual(result, []float64{math.NaN(), 10, 10, 20, 20}) {
+		t.Log("TestProject failed without the bar in progress. Result was: ", result, " Error: ", err)
+		t.Fail()
+	}
+	_, err = Project([]float64{10}, &td, "monthly", false)
+	if err == nil {
+		t.Log("TestProject should fail for a missing higher time frame.")
+		t.Fail()
+	}
+}
+
+func TestProjectResampledIndicator(t *testing.T) {
+	var raw marketdata.TickerData
+	// Three weeks from Monday 1/2/2017 to Friday 1/20/2017.
+	for day := 2; day <= 20; day++ {
+		date := time.Date(2017, 1, day, 0, 0, 0, 0, time.UTC)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		price := float64(day)
+		raw.Date = append(raw.Date, date)
+		raw.Open = append(raw.Open, price)
+		raw.High = append(raw.High, price+1)
+		raw.Low = append(raw.Low, price-1)
+		raw.Close = append(raw.Close, price)
+		raw.Volume = append(raw.Volume, 100)
+	}
+	td := marketdata.ProcessRawTickerData(&raw, &marketdata.TickerSplitData{}, "daily", []string{"id", "weekly_id"}, []string{"weekly"})
+	weekly, err := marketdata.Resample(&td, "weekly")
+	if err != nil || !approxEqual(weekly.Close, []float64{6, 13, 20}) {
+		t.Log("TestProjectResampledIndicator failed to resample. Result was: ", weekly.Close, " Error: ", err)
+		t.Fail()
+	}
+	result, err := Project(SMA(weekly.Close, 2), &td, "weekly", false)
+	nan := math.NaN()
+	expectedResult := []float64{nan, nan, nan, nan, nan, nan, nan, nan, nan, 9.5, 9.5, 9.5, 9.5, 9.5, 16.5}
+	if err != nil || !approxEqual(result, expectedResult) {
+		t.Log("TestProjectResampledIndicator failed. Result was: ", result, " but should be: ", expectedResult, " Error: ", err)
+		t.Fail()
+	}
+	if _, err = marketdata.Resample(&marketdata.TickerData{}, "weekly"); err == nil {
+		t.Log("TestProjectResampledIndicator should fail to resample ticker data without dates.")
+		t.Fail()
+	}
+}
+
+func approxEqual(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) != math.IsNaN(b[i]) || (!math.IsNaN(a[i]) && math.Abs(a[i]-b[i]) > 1e-9) {
+			return false
+		}
+	}
+	return true
+}
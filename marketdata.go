@@ -20,16 +20,22 @@ type DataReader interface {
 	readEventData(event *Event) (EventData, error)
 	readDividendData(symbol string, source DataSource) (TickerDividendData, error)
 	readSplitData(symbol string, source DataSource) (TickerSplitData, error)
+	readTickData(symbol string, tickConfig *ReadConfig) (TickData, error)
+	readQuoteData(symbol string, quoteConfig *ReadConfig) (QuoteData, error)
 	getDateFormat() string
 }
 
 type Data interface {
-	addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string) error
+	addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string, loc *time.Location) error
 	initialize(size int)
 }
 
 type DataWriter interface {
 	writeTickerData(symbol string, tickerData *TickerData, tickerConfig *WriteConfig) error
+	writeEventData(event *Event, eventData *EventData) error
+	writeSplitData(symbol string, tsd *TickerSplitData, source DataSource) error
+	writeDividendData(symbol string, tdd *TickerDividendData, source DataSource) error
+	writeYahooSplitDividendData(symbol string, tsd *TickerSplitData, tdd *TickerDividendData) error
 }
 
 type Event struct {
@@ -48,13 +54,15 @@ type ReadConfig struct {
 }
 
 type TickerForRead struct {
-	Symbol string
-	Config []ReadConfig
+	Symbol           string
+	Config           []ReadConfig
+	CorporateActions *CorporateActions
 }
 
 type WriteConfig struct {
-	TimeFrame string
-	Append    bool
+	TimeFrame         string
+	Append            bool
+	IncludeIncomplete bool
 }
 
 type TickerForWrite struct {
@@ -64,14 +72,22 @@ type TickerForWrite struct {
 }
 
 type TickerData struct {
-	Id          []int32
-	Date        []time.Time
-	Open        []float64
-	High        []float64
-	Close       []float64
-	Low         []float64
-	Volume      []int64
-	HigherTfIds map[string][]int32
+	Id           []int32
+	Date         []time.Time
+	Open         []float64
+	High         []float64
+	Close        []float64
+	Low          []float64
+	Volume       []int64
+	OpenInterest []int64
+	AdjFactor    []float64
+	VolumeFactor []float64
+	Vwap         []float64
+	BarCount     []int32
+	Incomplete   []bool
+	HigherTfIds  map[string][]int32
+	Precision    *int
+	Calendar     *Calendar
 }
 
 type TickerSplitData struct {
@@ -93,10 +109,17 @@ func ReadTickerData(dataReader DataReader, ticker *TickerForRead) (map[string]*T
 	data := make(map[string]*TickerData)
 	var err error
 	for _, config := range ticker.Config {
-		td, err := dataReader.readTickerData(ticker.Symbol, &config)
+		var td TickerData
+		td, err = dataReader.readTickerData(ticker.Symbol, &config)
 		if err != nil {
 			break
 		}
+		if ticker.CorporateActions != nil {
+			td, err = stitchRenamedTickerData(dataReader, &td, ticker.CorporateActions, &config)
+			if err != nil {
+				break
+			}
+		}
 		data[config.TimeFrame] = &td
 	}
 	return data, err
@@ -130,7 +153,11 @@ func WriteTickerData(dataWriter DataWriter, inTickerData *TickerData, ticker *Ti
 		if config.TimeFrame == ticker.BaseTimeFrame {
 			tdToWrite = inTickerData
 		} else {
-			higherTfTd, _ := createFromLowerTimeFrame(inTickerData, config.TimeFrame)
+			var higherTfTd TickerData
+			higherTfTd, err = buildFromLowerTimeFrame(inTickerData, config.TimeFrame, config.IncludeIncomplete)
+			if err != nil {
+				break
+			}
 			tdToWrite = &higherTfTd
 		}
 		err = dataWriter.writeTickerData(ticker.Symbol, tdToWrite, &config)
@@ -141,7 +168,25 @@ func WriteTickerData(dataWriter DataWriter, inTickerData *TickerData, ticker *Ti
 	return err
 }
 
+func WriteSplitData(dataWriter DataWriter, symbol string, tsd *TickerSplitData, source DataSource) error {
+	return dataWriter.writeSplitData(symbol, tsd, source)
+}
+
+func WriteDividendData(dataWriter DataWriter, symbol string, tdd *TickerDividendData, source DataSource) error {
+	return dataWriter.writeDividendData(symbol, tdd, source)
+}
+
+func WriteYahooSplitDividendData(dataWriter DataWriter, symbol string, tsd *TickerSplitData, tdd *TickerDividendData) error {
+	return dataWriter.writeYahooSplitDividendData(symbol, tsd, tdd)
+}
+
+// ProcessRawTickerData sorts inTd, adjusts it for the splits of tsd and adds the ids of
+// higherTfs. Tracking the adj_factor field also tracks volume_factor, so Unadjust can
+// restore volumes without the corporate action history.
 func ProcessRawTickerData(inTd *TickerData, tsd *TickerSplitData, baseTimeFrame string, additionalFields []string, higherTfs []string) TickerData {
+	if inArray("adj_factor", additionalFields) && !inArray("volume_factor", additionalFields) {
+		additionalFields = append(append([]string{}, additionalFields...), "volume_factor")
+	}
 	td := createSortedTickerData(inTd, additionalFields)
 	if tsd.Date != nil {
 		td.AdjustTickerDataForSplits(tsd)
@@ -178,6 +223,24 @@ func (td *TickerData) initialize(header map[string]int, size int) {
 			td.Close = make([]float64, size)
 		} else if key == "volume" {
 			td.Volume = make([]int64, size)
+		} else if key == "open_interest" {
+			td.OpenInterest = make([]int64, size)
+		} else if key == "adj_factor" {
+			td.AdjFactor = make([]float64, size)
+			for i := range td.AdjFactor {
+				td.AdjFactor[i] = 1
+			}
+		} else if key == "volume_factor" {
+			td.VolumeFactor = make([]float64, size)
+			for i := range td.VolumeFactor {
+				td.VolumeFactor[i] = 1
+			}
+		} else if key == "vwap" {
+			td.Vwap = make([]float64, size)
+		} else if key == "bar_count" {
+			td.BarCount = make([]int32, size)
+		} else if key == "incomplete" {
+			td.Incomplete = make([]bool, size)
 		} else if strings.Contains(key, "_id") {
 			if td.HigherTfIds == nil {
 				td.HigherTfIds = make(map[string][]int32)
@@ -187,7 +250,7 @@ func (td *TickerData) initialize(header map[string]int, size int) {
 	}
 }
 
-func (td *TickerData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string) error {
+func (td *TickerData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string, loc *time.Location) error {
 	var err error
 	var int64 int64
 	for key, value := range fieldIndex {
@@ -198,7 +261,7 @@ func (td *TickerData) addFromRecords(data []string, fieldIndex map[string]int, i
 			}
 			td.Id[index] = int32(int64)
 		} else if key == "date" {
-			td.Date[index], _ = time.Parse(dateFormat, data[value])
+			td.Date[index], _ = parseDate(dateFormat, data[value], loc)
 		} else if key == "open" {
 			td.Open[index], err = strconv.ParseFloat(data[value], 64)
 			if err != nil {
@@ -224,6 +287,37 @@ func (td *TickerData) addFromRecords(data []string, fieldIndex map[string]int, i
 			if err != nil {
 				return err
 			}
+		} else if key == "open_interest" {
+			td.OpenInterest[index], err = strconv.ParseInt(data[value], 10, 64)
+			if err != nil {
+				return err
+			}
+		} else if key == "adj_factor" {
+			td.AdjFactor[index], err = strconv.ParseFloat(data[value], 64)
+			if err != nil {
+				return err
+			}
+		} else if key == "volume_factor" {
+			td.VolumeFactor[index], err = strconv.ParseFloat(data[value], 64)
+			if err != nil {
+				return err
+			}
+		} else if key == "vwap" {
+			td.Vwap[index], err = strconv.ParseFloat(data[value], 64)
+			if err != nil {
+				return err
+			}
+		} else if key == "bar_count" {
+			int64, err = strconv.ParseInt(data[value], 10, 32)
+			if err != nil {
+				return err
+			}
+			td.BarCount[index] = int32(int64)
+		} else if key == "incomplete" {
+			td.Incomplete[index], err = strconv.ParseBool(data[value])
+			if err != nil {
+				return err
+			}
 		} else if strings.Contains(key, "_id") {
 			int64, err = strconv.ParseInt(data[value], 10, 32)
 			if err != nil {
@@ -237,14 +331,73 @@ func (td *TickerData) addFromRecords(data []string, fieldIndex map[string]int, i
 
 func (td *TickerData) adjustTickerDataForSplitEvent(index int32, beforeSplityQty int, afterSplitQty int) {
 	priceRatio := float64(beforeSplityQty) / float64(afterSplitQty)
-	volumeRatio := float32(afterSplitQty) / float32(beforeSplityQty)
-	dp := 2
+	volumeRatio := float64(afterSplitQty) / float64(beforeSplityQty)
+	dp := td.pricePrecision()
 	for x := index; x > -1; x-- {
-		td.Open[x] = td.Open[x] * priceRatio
-		td.High[x] = roundPlus((td.High[x] * priceRatio), dp)
-		td.Low[x] = roundPlus((td.Low[x] * priceRatio), dp)
-		td.Close[x] = roundPlus((td.Close[x] * priceRatio), dp)
-		td.Volume[x] = int64(float32(td.Volume[x]) * volumeRatio)
+		td.adjustTickerDataItem(int(x), priceRatio, volumeRatio, dp)
+	}
+}
+
+func (td *TickerData) adjustTickerDataItem(index int, priceRatio float64, volumeRatio float64, dp int) {
+	if td.VolumeFactor != nil {
+		td.VolumeFactor[index] = td.VolumeFactor[index] * volumeRatio
+	}
+	if td.AdjFactor != nil {
+		// The factor stays exact while the prices are rounded like untracked ones.
+		td.AdjFactor[index] = td.AdjFactor[index] * priceRatio
+	}
+	td.Open[index] = roundPlus((td.Open[index] * priceRatio), dp)
+	td.High[index] = roundPlus((td.High[index] * priceRatio), dp)
+	td.Low[index] = roundPlus((td.Low[index] * priceRatio), dp)
+	td.Close[index] = roundPlus((td.Close[index] * priceRatio), dp)
+	if td.Vwap != nil {
+		td.Vwap[index] = roundPlus((td.Vwap[index] * priceRatio), dp)
+	}
+	td.Volume[index] = int64(round(float64(td.Volume[index]) * volumeRatio))
+}
+
+// Unadjust converts adjusted prices and volumes back to the raw traded values. Prices
+// and volumes are restored from the stored AdjFactor and VolumeFactor columns when
+// present and otherwise derived from the corporate action history. Volumes are restored
+// exactly, while prices can be off by a tick as the adjusted prices were rounded.
+func (td *TickerData) Unadjust(ca *CorporateActions) {
+	l := len(td.Date)
+	priceFactors := make([]float64, l)
+	volumeFactors := make([]float64, l)
+	if ca != nil {
+		priceFactors, volumeFactors = ca.unadjustmentFactors(td)
+	} else {
+		for i := 0; i < l; i++ {
+			priceFactors[i] = 1
+			volumeFactors[i] = 1
+		}
+	}
+	if td.AdjFactor != nil {
+		copy(priceFactors, td.AdjFactor)
+	}
+	if td.VolumeFactor != nil {
+		copy(volumeFactors, td.VolumeFactor)
+	}
+	dp := td.pricePrecision()
+	for i := 0; i < l; i++ {
+		if priceFactors[i] != 1 {
+			td.Open[i] = roundPlus(td.Open[i]/priceFactors[i], dp)
+			td.High[i] = roundPlus(td.High[i]/priceFactors[i], dp)
+			td.Low[i] = roundPlus(td.Low[i]/priceFactors[i], dp)
+			td.Close[i] = roundPlus(td.Close[i]/priceFactors[i], dp)
+			if td.Vwap != nil {
+				td.Vwap[i] = roundPlus(td.Vwap[i]/priceFactors[i], dp)
+			}
+		}
+		if volumeFactors[i] != 1 {
+			td.Volume[i] = int64(round(float64(td.Volume[i]) / volumeFactors[i]))
+		}
+		if td.AdjFactor != nil {
+			td.AdjFactor[i] = 1
+		}
+		if td.VolumeFactor != nil {
+			td.VolumeFactor[i] = 1
+		}
 	}
 }
 
@@ -270,6 +423,24 @@ func (td *TickerData) addItem(inTd *TickerData, id int, inIndex int, index int)
 	if td.Volume != nil {
 		td.Volume[index] = inTd.Volume[inIndex]
 	}
+	if td.OpenInterest != nil && inTd.OpenInterest != nil {
+		td.OpenInterest[index] = inTd.OpenInterest[inIndex]
+	}
+	if td.AdjFactor != nil && inTd.AdjFactor != nil {
+		td.AdjFactor[index] = inTd.AdjFactor[inIndex]
+	}
+	if td.VolumeFactor != nil && inTd.VolumeFactor != nil {
+		td.VolumeFactor[index] = inTd.VolumeFactor[inIndex]
+	}
+	if td.Vwap != nil && inTd.Vwap != nil {
+		td.Vwap[index] = inTd.Vwap[inIndex]
+	}
+	if td.BarCount != nil && inTd.BarCount != nil {
+		td.BarCount[index] = inTd.BarCount[inIndex]
+	}
+	if td.Incomplete != nil && inTd.Incomplete != nil {
+		td.Incomplete[index] = inTd.Incomplete[inIndex]
+	}
 }
 
 func (td *TickerData) addItemFromLowerTimeFrame(inTd *TickerData, requestedTfField string, inIndex int32, index int32, date time.Time, open float64, high float64, low float64, close float64, volume int64) {
@@ -280,70 +451,35 @@ func (td *TickerData) addItemFromLowerTimeFrame(inTd *TickerData, requestedTfFie
 	td.Low[index] = low
 	td.Close[index] = close
 	td.Volume[index] = volume
+	if td.AdjFactor != nil && inTd.AdjFactor != nil {
+		td.AdjFactor[index] = inTd.AdjFactor[inIndex]
+	}
+	if td.VolumeFactor != nil && inTd.VolumeFactor != nil {
+		td.VolumeFactor[index] = inTd.VolumeFactor[inIndex]
+	}
 	for key := range td.HigherTfIds {
 		td.HigherTfIds[key][index] = inTd.HigherTfIds[key][inIndex]
 	}
 }
 
+// addHigherTimeFrameIds numbers the bars of td by the periods of higherTf. An intraday
+// td, like 1h, has a rank of 0 and is linked to every higher time frame.
 func (td *TickerData) addHigherTimeFrameIds(tdTf string, higherTf string) {
-	if tdTf == "daily" {
-		if higherTf == "weekly" {
-			td.addWeeklyIdToDailyData()
-		} else if higherTf == "monthly" {
-			td.addMonthlyIdToDailyData()
-		}
-	}
-}
-
-func (td *TickerData) addWeeklyIdToDailyData() {
-	_, ok := td.HigherTfIds["weekly_id"]
-	if !ok {
-		return
-	}
-	l := len(td.Date)
-	z := getIndexOfStartOfSecondWeek(td.Date)
-	if z == -1 {
+	if timeFrameRank(higherTf) <= timeFrameRank(tdTf) {
 		return
 	}
-	var i int
-	for i = z - 1; i > -1; i-- {
-		td.HigherTfIds["weekly_id"][i] = -1
-	}
-	weeklyId := int32(0)
-	td.HigherTfIds["weekly_id"][z] = weeklyId
-	for i = z + 1; i < l; i++ {
-		curDate := td.Date[i]
-		prevDate := td.Date[i-1]
-		if prevDate.Weekday() > curDate.Weekday() {
-			weeklyId++
-		}
-		td.HigherTfIds["weekly_id"][i] = weeklyId
-	}
-}
-
-func (td *TickerData) addMonthlyIdToDailyData() {
-	_, ok := td.HigherTfIds["monthly_id"]
+	ids, ok := td.HigherTfIds[higherTf+"_id"]
 	if !ok {
 		return
 	}
+	cal := td.calendar()
+	id := int32(-1)
 	l := len(td.Date)
-	z := getIndexOfStartOfSecondMonth(td.Date)
-	if z == -1 {
-		return
-	}
-	var i int
-	for i = z - 1; i > -1; i-- {
-		td.HigherTfIds["monthly_id"][i] = -1
-	}
-	monthlyId := int32(0)
-	td.HigherTfIds["monthly_id"][z] = monthlyId
-	for i = z + 1; i < l; i++ {
-		curDate := td.Date[i]
-		prevDate := td.Date[i-1]
-		if curDate.Month() != prevDate.Month() {
-			monthlyId++
+	for i := 0; i < l; i++ {
+		if i > 0 && cal.periodKey(td.Date[i], higherTf) != cal.periodKey(td.Date[i-1], higherTf) {
+			id++
 		}
-		td.HigherTfIds["monthly_id"][i] = monthlyId
+		ids[i] = id
 	}
 }
 
@@ -379,6 +515,30 @@ func getFields(td *TickerData, additionalFields []string, targetTimeFrame string
 		field["volume"] = i
 		i++
 	}
+	if td.OpenInterest != nil {
+		field["open_interest"] = i
+		i++
+	}
+	if td.AdjFactor != nil {
+		field["adj_factor"] = i
+		i++
+	}
+	if td.VolumeFactor != nil {
+		field["volume_factor"] = i
+		i++
+	}
+	if td.Vwap != nil {
+		field["vwap"] = i
+		i++
+	}
+	if td.BarCount != nil {
+		field["bar_count"] = i
+		i++
+	}
+	if td.Incomplete != nil {
+		field["incomplete"] = i
+		i++
+	}
 	if td.HigherTfIds != nil {
 		for key := range td.HigherTfIds {
 			if targetTimeFrame == "" || subStringInArray(key, linkedHtfs) {
@@ -394,7 +554,20 @@ func getFields(td *TickerData, additionalFields []string, targetTimeFrame string
 	return field
 }
 
-func createFromLowerTimeFrame(inTd *TickerData, requestedTimeFrame string) (TickerData, error) {
+// Resample creates the completed bars of timeFrame from td in memory, the bars that
+// WriteTickerData writes for a higher time frame. td needs open, high, low, close and
+// volume and the ids of timeFrame, which ProcessRawTickerData adds.
+func Resample(td *TickerData, timeFrame string) (TickerData, error) {
+	if len(td.Date) == 0 {
+		return TickerData{}, errors.New("Ticker data has no dates.")
+	}
+	if td.Id == nil || td.Open == nil || td.High == nil || td.Low == nil || td.Close == nil || td.Volume == nil {
+		return TickerData{}, errors.New("Ticker data needs id, open, high, low, close and volume to resample.")
+	}
+	return buildFromLowerTimeFrame(td, timeFrame, false)
+}
+
+func buildFromLowerTimeFrame(inTd *TickerData, requestedTimeFrame string, includeIncomplete bool) (TickerData, error) {
 	var err error
 	var td TickerData
 	l := int32(len(inTd.Id))
@@ -404,10 +577,25 @@ func createFromLowerTimeFrame(inTd *TickerData, requestedTimeFrame string) (Tick
 		return td, errors.New("Fields " + requestedTimeFrame + " does not exist in ticker data.")
 	}
 	fields := getFields(inTd, []string{}, requestedTimeFrame)
+	if includeIncomplete {
+		fields["bar_count"] = len(fields)
+		fields["incomplete"] = len(fields)
+	}
+	td.Precision = inTd.Precision
+	td.Calendar = inTd.Calendar
 	var lastCompletedTfIndex int32
 	lastCompletedTfIndex, err = getLastCompletedTimeFrameIndex(inTd, requestedTimeFrame)
+	lastIndex := lastCompletedTfIndex
+	if includeIncomplete {
+		lastIndex = l - 1
+	}
+	if lastIndex < 0 {
+		// No bars or no completed period.
+		td.initialize(fields, 0)
+		return td, err
+	}
 	//Account for the Ids starting at -1
-	rTfLength := inTd.HigherTfIds[rtfIdField][lastCompletedTfIndex] + 2
+	rTfLength := inTd.HigherTfIds[rtfIdField][lastIndex] + 2
 	td.initialize(fields, int(rTfLength))
 	rTfIndex := int32(0)
 	prevIdIndex := int32(0)
@@ -416,20 +604,10 @@ func createFromLowerTimeFrame(inTd *TickerData, requestedTimeFrame string) (Tick
 	high := inTd.High[0]
 	low := inTd.Low[0]
 	volume := inTd.Volume[0]
-	for i := int32(1); i < l; i++ {
-		if i == lastCompletedTfIndex {
-			if inTd.High[i] > high {
-				high = inTd.High[i]
-			}
-			if inTd.Low[i] < low {
-				low = inTd.Low[i]
-			}
-			volume = volume + inTd.Volume[i]
-			td.addItemFromLowerTimeFrame(inTd, rtfIdField, i, rTfIndex, date, open, high, low, inTd.Close[i], volume)
-			break
-		}
+	for i := int32(1); i <= lastIndex; i++ {
 		if inTd.HigherTfIds[rtfIdField][i] > inTd.HigherTfIds[rtfIdField][prevIdIndex] {
 			td.addItemFromLowerTimeFrame(inTd, rtfIdField, prevIdIndex, rTfIndex, date, open, high, low, inTd.Close[i-1], volume)
+			td.setBarCount(rTfIndex, i-prevIdIndex, false)
 			prevIdIndex = i
 			date = inTd.Date[i]
 			open = inTd.Open[i]
@@ -450,9 +628,63 @@ func createFromLowerTimeFrame(inTd *TickerData, requestedTimeFrame string) (Tick
 			volume = volume + inTd.Volume[i]
 		}
 	}
+	if rTfIndex < rTfLength {
+		td.addItemFromLowerTimeFrame(inTd, rtfIdField, prevIdIndex, rTfIndex, date, open, high, low, inTd.Close[lastIndex], volume)
+		td.setBarCount(rTfIndex, lastIndex-prevIdIndex+1, lastIndex != lastCompletedTfIndex)
+	}
+	if inTd.Vwap != nil {
+		td.addVwapFromLowerTimeFrame(inTd, rtfIdField, lastIndex)
+	}
+	if inTd.OpenInterest != nil {
+		td.addOpenInterestFromLowerTimeFrame(inTd, rtfIdField, lastIndex)
+	}
 	return td, err
 }
 
+func (td *TickerData) setBarCount(index int32, barCount int32, incomplete bool) {
+	if td.BarCount != nil {
+		td.BarCount[index] = barCount
+	}
+	if td.Incomplete != nil {
+		td.Incomplete[index] = incomplete
+	}
+}
+
+func (td *TickerData) addVwapFromLowerTimeFrame(inTd *TickerData, requestedTfField string, lastCompletedTfIndex int32) {
+	l := len(td.Vwap)
+	dp := inTd.pricePrecision()
+	// The notional is summed in ticks of the price precision, which are whole numbers, so
+	// the sum does not drift.
+	notional := make([]float64, l)
+	volume := make([]int64, l)
+	for i := int32(0); i <= lastCompletedTfIndex; i++ {
+		index := int(inTd.HigherTfIds[requestedTfField][i] + 1)
+		if index >= l {
+			break
+		}
+		notional[index] = notional[index] + float64(ToFixedPrice(inTd.Vwap[i], dp))*float64(inTd.Volume[i])
+		volume[index] = volume[index] + inTd.Volume[i]
+	}
+	for i := 0; i < l; i++ {
+		if volume[i] > 0 {
+			td.Vwap[i] = FixedPrice(round(notional[i] / float64(volume[i]))).Float64(dp)
+		}
+	}
+}
+
+func (td *TickerData) addOpenInterestFromLowerTimeFrame(inTd *TickerData, requestedTfField string, lastCompletedTfIndex int32) {
+	l := len(td.OpenInterest)
+	for i := int32(0); i <= lastCompletedTfIndex; i++ {
+		index := int(inTd.HigherTfIds[requestedTfField][i] + 1)
+		if index >= l {
+			break
+		}
+		td.OpenInterest[index] = inTd.OpenInterest[i]
+	}
+}
+
+// getLastCompletedTimeFrameIndex returns the index of the last bar of the last
+// completed period of timeFrame, or -1 when no period is completed.
 func getLastCompletedTimeFrameIndex(td *TickerData, timeFrame string) (int32, error) {
 	var err error
 	var lastTimeFrameId int32
@@ -462,18 +694,13 @@ func getLastCompletedTimeFrameIndex(td *TickerData, timeFrame string) (int32, er
 	if !ok {
 		return lastTimeFrameId, errors.New("Field " + timeFrame + " does not exist in ticker data.")
 	}
-	lastDate := td.Date[l-1]
-	if timeFrame == "weekly" {
-		if lastDate.Weekday().String() == "Friday" {
-			return int32(l - 1), err
-		}
-	} else if timeFrame == "monthly" {
-		if (lastDate.Month() != lastDate.AddDate(0, 0, 1).Month()) ||
-			(lastDate.Weekday().String() == "Friday" && lastDate.Month() != lastDate.AddDate(0, 0, 3).Month()) {
-			return int32(l - 1), err
-		}
+	if l == 0 {
+		return -1, err
 	}
-	var index int32
+	if td.calendar().isLastDayOfTimeFrame(td.Date[l-1], timeFrame) {
+		return int32(l - 1), err
+	}
+	index := int32(-1)
 	for i := l - 2; i >= 0; i-- {
 		if td.HigherTfIds[tfIdField][i] != td.HigherTfIds[tfIdField][i+1] {
 			index = i
@@ -497,6 +724,8 @@ func createSortedTickerData(inTd *TickerData, additionalFields []string) TickerD
 func createTickerDataFromAscOrder(inTd *TickerData, fields map[string]int) TickerData {
 	var td TickerData
 	td.initialize(fields, len(inTd.Date))
+	td.Precision = inTd.Precision
+	td.Calendar = inTd.Calendar
 	l := len(inTd.Date)
 	var i int
 	for i = 0; i < l; i++ {
@@ -508,6 +737,8 @@ func createTickerDataFromAscOrder(inTd *TickerData, fields map[string]int) Ticke
 func createTickerDataFromDescOrder(inTd *TickerData, fields map[string]int) TickerData {
 	var td TickerData
 	td.initialize(fields, len(inTd.Date))
+	td.Precision = inTd.Precision
+	td.Calendar = inTd.Calendar
 	l := len(inTd.Date)
 	var i int
 	id := -1
@@ -535,11 +766,14 @@ func (tdd *TickerDividendData) initialize(size int) {
 	tdd.Amount = make([]float64, size)
 }
 
-func (tdd *TickerDividendData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string) error {
+func (tdd *TickerDividendData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string, loc *time.Location) error {
 	var err error
 	for key, value := range fieldIndex {
 		if key == "date" {
-			tdd.Date[index], _ = time.Parse(dateFormat, strings.TrimSpace(data[value]))
+			tdd.Date[index], err = parseDate(dateFormat, strings.TrimSpace(data[value]), loc)
+			if err != nil {
+				return err
+			}
 		} else if key == "dividend" {
 			tdd.Amount[index], err = strconv.ParseFloat(strings.TrimSpace(data[value]), 64)
 			if err != nil {
@@ -592,12 +826,15 @@ func (tsd *TickerSplitData) initialize(size int) {
 	tsd.AfterSplitQty = make([]int, size)
 }
 
-func (tsd *TickerSplitData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string) error {
+func (tsd *TickerSplitData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string, loc *time.Location) error {
 	var err error
 	var int64val int64
 	for key, value := range fieldIndex {
 		if key == "date" {
-			tsd.Date[index], _ = time.Parse(dateFormat, strings.TrimSpace(data[value]))
+			tsd.Date[index], err = parseDate(dateFormat, strings.TrimSpace(data[value]), loc)
+			if err != nil {
+				return err
+			}
 		} else if key == "split" {
 			splitData := strings.Split(data[value], ":")
 			int64val, err = strconv.ParseInt(splitData[1], 10, 16)
@@ -630,13 +867,34 @@ func sortSplitDataInAscOrder(tsd *TickerSplitData, fields map[string]int) Ticker
 
 func getIndexOfDateValue(dates []time.Time, date time.Time) int32 {
 	for i := range dates {
-		if dates[i] == date {
+		if dates[i].Equal(date) {
 			return int32(i)
 		}
 	}
 	return -1
 }
 
+func parseDate(dateFormat string, value string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		return time.Parse(dateFormat, value)
+	}
+	date, err := time.ParseInLocation(dateFormat, value, loc)
+	return date.In(loc), err
+}
+
+func formatDate(date time.Time, dateFormat string, loc *time.Location) string {
+	if loc != nil {
+		date = date.In(loc)
+	}
+	return date.Format(dateFormat)
+}
+
+func (td *TickerData) InLocation(loc *time.Location) {
+	for i := range td.Date {
+		td.Date[i] = td.Date[i].In(loc)
+	}
+}
+
 func numDecimalPlaces(v float64) int {
 	s := strconv.FormatFloat(v, 'f', -1, 64)
 	i := strings.IndexByte(s, '.')
@@ -665,44 +923,18 @@ func dataInDescOrder(date []time.Time) bool {
 func getLinkedHigherTimeFrames(targetTimeFrame string) []string {
 	switch tf := targetTimeFrame; tf {
 	case "daily":
-		return []string{"weekly", "monthly"}
+		return []string{"weekly", "monthly", "quarterly", "yearly"}
 	case "weekly":
-		return []string{"monthly"}
+		return []string{"monthly", "quarterly", "yearly"}
+	case "monthly":
+		return []string{"quarterly", "yearly"}
+	case "quarterly":
+		return []string{"yearly"}
 	default:
 		return []string{}
 	}
 }
 
-func getIndexOfStartOfSecondWeek(date []time.Time) int {
-	l := len(date)
-	if l <= 1 {
-		return -1
-	}
-	for i := 1; i < l; i++ {
-		curDate := date[i]
-		prevDate := date[i-1]
-		if prevDate.Weekday() > curDate.Weekday() {
-			return i
-		}
-	}
-	return -1
-}
-
-func getIndexOfStartOfSecondMonth(date []time.Time) int {
-	l := len(date)
-	if l <= 1 {
-		return -1
-	}
-	for i := 1; i < l; i++ {
-		curDate := date[i]
-		prevDate := date[i-1]
-		if curDate.Month() != prevDate.Month() {
-			return i
-		}
-	}
-	return -1
-}
-
 func inArray(value string, array []string) bool {
 	for _, item := range array {
 		if strings.ToLower(value) == strings.ToLower(item) {
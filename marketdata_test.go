@@ -13,8 +13,8 @@ import (
 func TestWriteTickerData(t *testing.T) {
 	dateFormat := "1/2/2006"
 	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
-	csvWriter := CsvWriter{outputPath, "{ticker}-{timeframe}.csv", dateFormat}
-	tickerForWrite := TickerForWrite{"testticker", "daily", []WriteConfig{{"daily", false}, {"weekly", false}, {"monthly", false}}}
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: dateFormat}
+	tickerForWrite := TickerForWrite{"testticker", "daily", []WriteConfig{{TimeFrame: "daily", Append: false}, {TimeFrame: "weekly", Append: false}, {TimeFrame: "monthly", Append: false}}}
 	processedTd := getExpectedDailyDataWithWeeklyAndMonthlyIds()
 	var err error
 	var result []byte
@@ -198,7 +198,7 @@ func TestCreateFromLowerTimeFrame(t *testing.T) {
 	for _, tc := range testCases {
 		inputTickerData, _ := getTestTickerData("asc", tc.dataSubtractAmount)
 		processedTd := ProcessRawTickerData(&inputTickerData, &tsd, baseTimeFrame, tc.addFields, tc.higherTfs)
-		newTfTickerData, _ := createFromLowerTimeFrame(&processedTd, tc.targetTimeFrame)
+		newTfTickerData, _ := Resample(&processedTd, tc.targetTimeFrame)
 		expectedResult, _ := getExpectedHigherTfData(tc.expectedResultKey)
 		if !reflect.DeepEqual(newTfTickerData, expectedResult) {
 			t.Log("TestCreateFromLowerTimeFrame test case ", tc.name, " failed to create TickerData from a lower time frame. Result was: ", newTfTickerData, " but should be: ", expectedResult)
@@ -444,3 +444,68 @@ func createDates(dates []string, dateFormat string) []time.Time {
 	}
 	return realDates
 }
+
+func TestWriteTickerDataIncludingIncompleteBar(t *testing.T) {
+	dateFormat := "1/2/2006"
+	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: dateFormat}
+	csvReader := CsvReader{DataPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: dateFormat}
+	tickerForWrite := TickerForWrite{"testticker", "daily", []WriteConfig{{TimeFrame: "monthly", IncludeIncomplete: true}}}
+	processedTd := getExpectedDailyDataWithWeeklyAndMonthlyIds()
+	err := WriteTickerData(csvWriter, &processedTd, &tickerForWrite)
+	resultingFile := outputPath + "testticker-monthly.csv"
+	result, _ := ioutil.ReadFile(resultingFile)
+	expectedValue := "id,date,open,high,low,close,volume,bar_count,incomplete\n" +
+		"0,11/28/2016,221.16,221.82,220.17,220.38,259751000,3,false\n" +
+		"1,12/1/2016,220.73,228.34,219.15,226.27,1721219500,21,false\n" +
+		"2,1/2/2017,226.02,226.73,226,226.27,41054400,1,true\n"
+	if err != nil || string(result) != expectedValue {
+		t.Log("Failed to write incomplete bar. Result was: ", string(result), " but should be: ", expectedValue)
+		t.Fail()
+	}
+	readTd, err := csvReader.readTickerData("testticker", &ReadConfig{TimeFrame: "monthly"})
+	if err != nil || !reflect.DeepEqual(readTd.Incomplete, []bool{false, false, true}) || !reflect.DeepEqual(readTd.BarCount, []int32{3, 21, 1}) {
+		t.Log("Failed to read back incomplete bar. Result was: ", readTd, " Error: ", err)
+		t.Fail()
+	}
+	os.Remove(resultingFile)
+}
+
+func TestBuildFromLowerTimeFrameWithoutCompletedPeriod(t *testing.T) {
+	var partial TickerData
+	partial.Id = []int32{0, 1}
+	partial.Date = createDates([]string{"1/3/2017", "1/4/2017"}, "1/2/2006")
+	partial.Open = []float64{1, 2}
+	partial.High = []float64{1, 2}
+	partial.Low = []float64{1, 2}
+	partial.Close = []float64{1, 2}
+	partial.Volume = []int64{10, 20}
+	partial.HigherTfIds = map[string][]int32{"weekly_id": {-1, -1}}
+	single := copyTickerDataRange(&partial, 0, 1)
+	empty := copyTickerDataRange(&partial, 0, 0)
+	testCases := []struct {
+		name               string
+		td                 TickerData
+		includeIncomplete  bool
+		expectedIncomplete []bool
+	}{
+		{"'Partial period'", partial, true, []bool{true}},
+		{"'Single bar of a partial period'", single, true, []bool{true}},
+		{"'Partial period without incomplete bars'", partial, false, nil},
+		{"'No bars'", empty, true, []bool{}},
+	}
+	for _, tc := range testCases {
+		result, err := buildFromLowerTimeFrame(&tc.td, "weekly", tc.includeIncomplete)
+		if err != nil || len(result.Date) != len(tc.expectedIncomplete) || !reflect.DeepEqual(result.Incomplete, tc.expectedIncomplete) {
+			t.Log("TestBuildFromLowerTimeFrameWithoutCompletedPeriod test case ", tc.name, " failed. Result was: ", result.Date, result.Incomplete, " but should be: ", tc.expectedIncomplete, " Error: ", err)
+			t.Fail()
+		}
+	}
+	outputPath := t.TempDir() + string(os.PathSeparator)
+	csvWriter := CsvWriter{OutputPath: outputPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	err := WriteTickerData(csvWriter, &empty, &TickerForWrite{"testticker", "daily", []WriteConfig{{TimeFrame: "weekly", IncludeIncomplete: true}}})
+	if err != nil {
+		t.Log("TestBuildFromLowerTimeFrameWithoutCompletedPeriod failed to write ticker data without bars. Error: ", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,156 @@
+package marketdata
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergeSource is ticker data of one source. Sources passed to MergeTickerData are in
+// order of priority, the first one being preferred.
+type MergeSource struct {
+	Source DataSource
+	Data   *TickerData
+}
+
+// Discrepancy is a value of Source that differs from the value of Reference, the source
+// the merged bar was taken from, by more than the tolerance of Field.
+type Discrepancy struct {
+	Date           time.Time
+	Field          string
+	Source         DataSource
+	Value          float64
+	Reference      DataSource
+	ReferenceValue float64
+}
+
+// MergedTickerData holds the merged bars in ascending order, the source each bar was
+// taken from and the disagreements found between the sources.
+type MergedTickerData struct {
+	TickerData    TickerData
+	Source        []DataSource
+	Discrepancies []Discrepancy
+}
+
+// MergeTickerData aligns the ticker data of the same symbol and time frame of several
+// sources by date. Each bar is taken from the source with the highest priority that
+// has the date, so gaps of a source are filled from the sources after it. Tolerance
+// maps the fields to compare, like "close" or "volume", to the largest relative
+// difference allowed between the chosen bar and the bars of the other sources. The
+// merged data has the fields of the first source without linked higher time frame
+// ids, which ProcessRawTickerData computes. Every other source needs those fields, so
+// a gap is never filled with missing values. All sources need to be read in the same
+// location, as bars are aligned by their time.
+func MergeTickerData(sources []MergeSource, tolerance map[string]float64) (MergedTickerData, error) {
+	var merged MergedTickerData
+	if len(sources) == 0 {
+		return merged, errors.New("No sources to merge.")
+	}
+	for _, source := range sources {
+		if source.Data == nil || len(source.Data.Date) == 0 {
+			return merged, errors.New("Source " + string(source.Source) + " has no dates.")
+		}
+		// Daily bars of different locations are different instants, so they never align.
+		if loc, firstLoc := source.Data.Date[0].Location().String(), sources[0].Data.Date[0].Location().String(); loc != firstLoc {
+			return merged, errors.New("Source " + string(source.Source) + " has dates in " + loc + " but source " + string(sources[0].Source) + " in " + firstLoc + ".")
+		}
+	}
+	fields := []string{}
+	for field := range tolerance {
+		if !isMergeField(field) {
+			return merged, errors.New("Invalid merge field " + field + ".")
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	// columns[s][field] holds the values of field of source s, nil if it has none.
+	columns := make([]map[string][]float64, len(sources))
+	indexes := make([]map[int64]int, len(sources))
+	dates := []time.Time{}
+	for s, source := range sources {
+		columns[s] = make(map[string][]float64)
+		for _, field := range fields {
+			if values, ok := getFloatColumn(source.Data, field); ok {
+				columns[s][field] = values
+			}
+		}
+		indexes[s] = make(map[int64]int)
+		for i, date := range source.Data.Date {
+			key := date.UnixNano()
+			if _, exists := indexes[s][key]; exists {
+				return merged, errors.New("Source " + string(source.Source) + " has duplicate date " + date.String() + ".")
+			}
+			indexes[s][key] = i
+			if !dateIndexed(indexes[:s], key) {
+				dates = append(dates, date)
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	first := sources[0].Data
+	mergeFields := getFields(first, []string{}, "")
+	for key := range mergeFields {
+		if strings.HasSuffix(key, "_id") {
+			delete(mergeFields, key)
+		}
+	}
+	for _, source := range sources[1:] {
+		sourceFields := getFields(source.Data, []string{}, "")
+		for key := range mergeFields {
+			if _, exists := sourceFields[key]; !exists && key != "id" {
+				return merged, errors.New("Source " + string(source.Source) + " has no " + key + " field like source " + string(sources[0].Source) + ".")
+			}
+		}
+	}
+	mergeFields["id"] = len(mergeFields)
+	merged.TickerData.initialize(mergeFields, len(dates))
+	merged.TickerData.Precision = first.Precision
+	merged.TickerData.Calendar = first.Calendar
+	merged.Source = make([]DataSource, len(dates))
+	for i, date := range dates {
+		key := date.UnixNano()
+		chosen := -1
+		for s := range sources {
+			if _, exists := indexes[s][key]; exists {
+				chosen = s
+				break
+			}
+		}
+		inIndex := indexes[chosen][key]
+		merged.TickerData.addItem(sources[chosen].Data, i, inIndex, i)
+		merged.Source[i] = sources[chosen].Source
+		for _, field := range fields {
+			reference, ok := columns[chosen][field]
+			if !ok {
+				continue
+			}
+			for s := chosen + 1; s < len(sources); s++ {
+				index, exists := indexes[s][key]
+				values, ok := columns[s][field]
+				if !exists || !ok {
+					continue
+				}
+				if math.Abs(values[index]-reference[inIndex]) > tolerance[field]*math.Abs(reference[inIndex]) {
+					merged.Discrepancies = append(merged.Discrepancies, Discrepancy{date, field, sources[s].Source, values[index], sources[chosen].Source, reference[inIndex]})
+				}
+			}
+		}
+	}
+	return merged, nil
+}
+
+func isMergeField(field string) bool {
+	return field == "open" || field == "high" || field == "low" || field == "close" || field == "volume" || field == "open_interest" || field == "vwap" || field == "adj_factor" || field == "volume_factor"
+}
+
+func dateIndexed(indexes []map[int64]int, key int64) bool {
+	for _, index := range indexes {
+		if _, exists := index[key]; exists {
+			return true
+		}
+	}
+	return false
+}
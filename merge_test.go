@@ -0,0 +1,67 @@
+package marketdata
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeTickerData(t *testing.T) {
+	var vendor, yahoo TickerData
+	vendor.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/6/2017"}, "1/2/2006")
+	vendor.Close = []float64{10, 11, 13}
+	vendor.Volume = []int64{100, 110, 130}
+	vendor.HigherTfIds = map[string][]int32{"weekly_id": {-1, -1, -1}}
+	vendor.Precision = NewPrecision(3)
+	// Newest first like Yahoo files.
+	yahoo.Date = createDates([]string{"1/6/2017", "1/5/2017", "1/4/2017", "1/3/2017"}, "1/2/2006")
+	yahoo.Close = []float64{13.01, 12, 11.5, 10}
+	yahoo.Volume = []int64{130, 120, 200, 100}
+	result, err := MergeTickerData([]MergeSource{{"VENDOR", &vendor}, {YAHOO, &yahoo}}, map[string]float64{"close": 0.01, "volume": 0.5})
+	expectedDates := createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017", "1/6/2017"}, "1/2/2006")
+	expectedDiscrepancies := []Discrepancy{
+		{expectedDates[1], "close", YAHOO, 11.5, "VENDOR", 11},
+		{expectedDates[1], "volume", YAHOO, 200, "VENDOR", 110},
+	}
+	td := result.TickerData
+	if err != nil || !reflect.DeepEqual(td.Date, expectedDates) || !reflect.DeepEqual(td.Close, []float64{10, 11, 12, 13}) ||
+		!reflect.DeepEqual(td.Volume, []int64{100, 110, 120, 130}) || !reflect.DeepEqual(td.Id, []int32{0, 1, 2, 3}) || td.HigherTfIds != nil || td.pricePrecision() != 3 {
+		t.Log("TestMergeTickerData failed. Result was: ", td, " Error: ", err)
+		t.Fail()
+	}
+	if !reflect.DeepEqual(result.Source, []DataSource{"VENDOR", "VENDOR", YAHOO, "VENDOR"}) {
+		t.Log("TestMergeTickerData failed to record the source of each bar. Result was: ", result.Source)
+		t.Fail()
+	}
+	if !reflect.DeepEqual(result.Discrepancies, expectedDiscrepancies) {
+		t.Log("TestMergeTickerData failed to report discrepancies. Result was: ", result.Discrepancies, " but should be: ", expectedDiscrepancies)
+		t.Fail()
+	}
+}
+
+func TestMergeTickerDataHandlesErrors(t *testing.T) {
+	var td, duplicates, withOpen, newYork TickerData
+	td.Date = createDates([]string{"1/3/2017"}, "1/2/2006")
+	newYork.Date = []time.Time{time.Date(2017, 1, 3, 0, 0, 0, 0, time.FixedZone("EST", -5*3600))}
+	withOpen.Date = createDates([]string{"1/4/2017"}, "1/2/2006")
+	withOpen.Open = []float64{1}
+	duplicates.Date = createDates([]string{"1/3/2017", "1/3/2017"}, "1/2/2006")
+	testCases := []struct {
+		name      string
+		sources   []MergeSource
+		tolerance map[string]float64
+	}{
+		{"'No sources'", []MergeSource{}, nil},
+		{"'Source without dates'", []MergeSource{{OTHER, &TickerData{}}}, nil},
+		{"'Duplicate dates'", []MergeSource{{OTHER, &duplicates}}, nil},
+		{"'Unknown field'", []MergeSource{{OTHER, &td}}, map[string]float64{"price": 0}},
+		{"'Fallback source without a field'", []MergeSource{{OTHER, &withOpen}, {YAHOO, &td}}, nil},
+		{"'Sources in different locations'", []MergeSource{{OTHER, &td}, {YAHOO, &newYork}}, nil},
+	}
+	for _, tc := range testCases {
+		if _, err := MergeTickerData(tc.sources, tc.tolerance); err == nil {
+			t.Log("TestMergeTickerDataHandlesErrors test case ", tc.name, " should fail.")
+			t.Fail()
+		}
+	}
+}
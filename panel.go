@@ -0,0 +1,190 @@
+package marketdata
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+type DateIndexMode string
+
+const (
+	UNION_INDEX        DateIndexMode = "UNION"
+	INTERSECTION_INDEX DateIndexMode = "INTERSECTION"
+)
+
+type MissingValuePolicy string
+
+const (
+	MISSING_AS_NAN       MissingValuePolicy = "NAN"
+	MISSING_FORWARD_FILL MissingValuePolicy = "FORWARD_FILL"
+	MISSING_AS_ZERO      MissingValuePolicy = "ZERO"
+	MISSING_DROP         MissingValuePolicy = "DROP"
+)
+
+// Panel aligns the ticker data of several symbols on a common date index. Column
+// matrices are indexed by date first and symbol second, in the order of Date and Symbols.
+type Panel struct {
+	Symbols       []string
+	Date          []time.Time
+	MissingPolicy MissingValuePolicy
+	data          map[string]*TickerData
+	position      map[string][]int
+}
+
+func NewPanel(data map[string]*TickerData, mode DateIndexMode, policy MissingValuePolicy) Panel {
+	var panel Panel
+	panel.MissingPolicy = policy
+	panel.data = data
+	panel.position = make(map[string][]int)
+	for symbol := range data {
+		panel.Symbols = append(panel.Symbols, symbol)
+	}
+	sort.Strings(panel.Symbols)
+	count := make(map[int64]int)
+	dates := make(map[int64]time.Time)
+	for _, symbol := range panel.Symbols {
+		for _, date := range data[symbol].Date {
+			count[date.UnixNano()]++
+			dates[date.UnixNano()] = date
+		}
+	}
+	for key, date := range dates {
+		if mode != INTERSECTION_INDEX || count[key] == len(panel.Symbols) {
+			panel.Date = append(panel.Date, date)
+		}
+	}
+	sort.Slice(panel.Date, func(i, j int) bool { return panel.Date[i].Before(panel.Date[j]) })
+	for _, symbol := range panel.Symbols {
+		index := make(map[int64]int)
+		for i, date := range data[symbol].Date {
+			index[date.UnixNano()] = i
+		}
+		position := make([]int, len(panel.Date))
+		for i, date := range panel.Date {
+			p, ok := index[date.UnixNano()]
+			if !ok {
+				p = -1
+			}
+			position[i] = p
+		}
+		panel.position[symbol] = position
+	}
+	if policy == MISSING_DROP {
+		panel.dropIncompleteDates()
+	}
+	return panel
+}
+
+func (panel *Panel) dropIncompleteDates() {
+	var dates []time.Time
+	keep := make([]int, 0, len(panel.Date))
+	for i, date := range panel.Date {
+		complete := true
+		for _, symbol := range panel.Symbols {
+			if panel.position[symbol][i] == -1 {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			dates = append(dates, date)
+			keep = append(keep, i)
+		}
+	}
+	for _, symbol := range panel.Symbols {
+		position := make([]int, len(keep))
+		for i, k := range keep {
+			position[i] = panel.position[symbol][k]
+		}
+		panel.position[symbol] = position
+	}
+	panel.Date = dates
+}
+
+func (panel *Panel) Open() [][]float64 {
+	matrix, _ := panel.Column("open")
+	return matrix
+}
+
+func (panel *Panel) High() [][]float64 {
+	matrix, _ := panel.Column("high")
+	return matrix
+}
+
+func (panel *Panel) Low() [][]float64 {
+	matrix, _ := panel.Column("low")
+	return matrix
+}
+
+func (panel *Panel) Close() [][]float64 {
+	matrix, _ := panel.Column("close")
+	return matrix
+}
+
+func (panel *Panel) Volume() [][]float64 {
+	matrix, _ := panel.Column("volume")
+	return matrix
+}
+
+// Column returns the aligned values of field with missing values filled according
+// to the missing value policy of the panel.
+func (panel *Panel) Column(field string) ([][]float64, error) {
+	matrix := make([][]float64, len(panel.Date))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(panel.Symbols))
+	}
+	for s, symbol := range panel.Symbols {
+		column, ok := getFloatColumn(panel.data[symbol], field)
+		if !ok {
+			return nil, errors.New("Field " + field + " does not exist in ticker data of " + symbol + ".")
+		}
+		previous := math.NaN()
+		for i, p := range panel.position[symbol] {
+			if p > -1 {
+				matrix[i][s] = column[p]
+				previous = column[p]
+			} else if panel.MissingPolicy == MISSING_FORWARD_FILL {
+				matrix[i][s] = previous
+			} else if panel.MissingPolicy == MISSING_AS_ZERO {
+				matrix[i][s] = 0
+			} else {
+				matrix[i][s] = math.NaN()
+			}
+		}
+	}
+	return matrix, nil
+}
+
+func getFloatColumn(td *TickerData, field string) ([]float64, bool) {
+	switch field {
+	case "open":
+		return td.Open, td.Open != nil
+	case "high":
+		return td.High, td.High != nil
+	case "low":
+		return td.Low, td.Low != nil
+	case "close":
+		return td.Close, td.Close != nil
+	case "adj_factor":
+		return td.AdjFactor, td.AdjFactor != nil
+	case "volume_factor":
+		return td.VolumeFactor, td.VolumeFactor != nil
+	case "vwap":
+		return td.Vwap, td.Vwap != nil
+	case "volume":
+		return int64ToFloat(td.Volume), td.Volume != nil
+	case "open_interest":
+		return int64ToFloat(td.OpenInterest), td.OpenInterest != nil
+	}
+	return nil, false
+}
+
+func int64ToFloat(values []int64) []float64 {
+	result := make([]float64, len(values))
+	for i, value := range values {
+		result[i] = float64(value)
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package marketdata
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPanel(t *testing.T) {
+	nan := math.NaN()
+	testCases := []struct {
+		name          string
+		mode          DateIndexMode
+		policy        MissingValuePolicy
+		expectedDates int
+		expectedClose [][]float64
+	}{
+		{"'Union index with NaN for missing values'", UNION_INDEX, MISSING_AS_NAN, 4, [][]float64{{10, nan}, {11, 20}, {nan, 21}, {13, 22}}},
+		{"'Union index with forward fill'", UNION_INDEX, MISSING_FORWARD_FILL, 4, [][]float64{{10, nan}, {11, 20}, {11, 21}, {13, 22}}},
+		{"'Union index with zero for missing values'", UNION_INDEX, MISSING_AS_ZERO, 4, [][]float64{{10, 0}, {11, 20}, {0, 21}, {13, 22}}},
+		{"'Union index dropping incomplete dates'", UNION_INDEX, MISSING_DROP, 2, [][]float64{{11, 20}, {13, 22}}},
+		{"'Intersection index'", INTERSECTION_INDEX, MISSING_AS_NAN, 2, [][]float64{{11, 20}, {13, 22}}},
+	}
+	var aaa, bbb TickerData
+	aaa.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/6/2017"}, "1/2/2006")
+	aaa.Close = []float64{10, 11, 13}
+	bbb.Date = createDates([]string{"1/4/2017", "1/5/2017", "1/6/2017"}, "1/2/2006")
+	bbb.Close = []float64{20, 21, 22}
+	for _, tc := range testCases {
+		panel := NewPanel(map[string]*TickerData{"bbb": &bbb, "aaa": &aaa}, tc.mode, tc.policy)
+		result := panel.Close()
+		if len(panel.Date) != tc.expectedDates || panel.Symbols[0] != "aaa" || !equalMatrix(result, tc.expectedClose) {
+			t.Log("TestPanel test case ", tc.name, " failed. Result was: ", panel.Date, result, " but should be: ", tc.expectedClose)
+			t.Fail()
+		}
+	}
+	panel := NewPanel(map[string]*TickerData{"aaa": &aaa}, UNION_INDEX, MISSING_AS_NAN)
+	if _, err := panel.Column("vwap"); err == nil {
+		t.Log("TestPanel should fail for a column that does not exist.")
+		t.Fail()
+	}
+}
+
+func equalMatrix(a [][]float64, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] && !(math.IsNaN(a[i][j]) && math.IsNaN(b[i][j])) {
+				return false
+			}
+		}
+	}
+	return true
+}
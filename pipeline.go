@@ -0,0 +1,198 @@
+package marketdata
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// PipelineConfig describes a batch run that reads the base time frame of every symbol
+// from Source, optionally adjusts it for corporate actions and writes each of the
+// time frames to Output. Dates of Start and End use the date format of the source.
+type PipelineConfig struct {
+	Source            PipelineSource      `json:"source"`
+	Output            PipelineOutput      `json:"output"`
+	Symbols           []string            `json:"symbols"`
+	BaseTimeFrame     string              `json:"base_timeframe"`
+	TimeFrames        []string            `json:"timeframes"`
+	Start             string              `json:"start"`
+	End               string              `json:"end"`
+	IncludeIncomplete bool                `json:"include_incomplete"`
+	Append            bool                `json:"append"`
+	Adjustments       PipelineAdjustments `json:"adjustments"`
+}
+
+// PipelineSource describes the ticker files to read. Without price_precision or an
+// entry of the symbol in symbol_precision prices have 2 decimals, unless
+// detect_precision is set.
+type PipelineSource struct {
+	DataPath        string         `json:"data_path"`
+	FileNamePattern string         `json:"file_name_pattern"`
+	DateFormat      string         `json:"date_format"`
+	Location        string         `json:"location"`
+	PricePrecision  *int           `json:"price_precision"`
+	SymbolPrecision map[string]int `json:"symbol_precision"`
+	DetectPrecision bool           `json:"detect_precision"`
+}
+
+type PipelineOutput struct {
+	OutputPath      string `json:"output_path"`
+	FileNamePattern string `json:"file_name_pattern"`
+	DateFormat      string `json:"date_format"`
+	Location        string `json:"location"`
+}
+
+// PipelineAdjustments holds the file name patterns of the split and dividend files,
+// which are read from the data path of the source. Their dates use ActionDateFormat,
+// or the date format of the source when it is empty.
+type PipelineAdjustments struct {
+	SplitFileNamePattern    string `json:"split_file_name_pattern"`
+	DividendFileNamePattern string `json:"dividend_file_name_pattern"`
+	YahooFileNamePattern    string `json:"yahoo_file_name_pattern"`
+	ActionDateFormat        string `json:"action_date_format"`
+}
+
+type PipelineStatus struct {
+	Symbol string
+	Bars   int
+	Err    error
+}
+
+func ReadPipelineConfig(r io.Reader) (PipelineConfig, error) {
+	var config PipelineConfig
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, errors.New("Pipeline Config Error: " + err.Error())
+	}
+	if len(config.Symbols) == 0 || config.BaseTimeFrame == "" || len(config.TimeFrames) == 0 {
+		return config, errors.New("Pipeline Config Error: symbols, base_timeframe and timeframes are required.")
+	}
+	return config, nil
+}
+
+func LoadPipelineConfig(fileName string) (PipelineConfig, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return PipelineConfig{}, errors.New("File Open Error: " + err.Error())
+	}
+	defer f.Close()
+	return ReadPipelineConfig(f)
+}
+
+// RunPipeline processes every symbol of the config and reports the status of each.
+// A failing symbol does not stop the run.
+func RunPipeline(config *PipelineConfig) []PipelineStatus {
+	statuses := make([]PipelineStatus, len(config.Symbols))
+	csvReader, csvWriter, readConfig, err := config.build()
+	for i, symbol := range config.Symbols {
+		statuses[i].Symbol = symbol
+		if err != nil {
+			statuses[i].Err = err
+			continue
+		}
+		statuses[i].Bars, statuses[i].Err = config.runSymbol(symbol, csvReader, csvWriter, readConfig)
+	}
+	return statuses
+}
+
+func (config *PipelineConfig) build() (CsvReader, CsvWriter, ReadConfig, error) {
+	var csvReader CsvReader
+	var csvWriter CsvWriter
+	readConfig := ReadConfig{TimeFrame: config.BaseTimeFrame}
+	sourceLoc, err := loadLocation(config.Source.Location)
+	if err != nil {
+		return csvReader, csvWriter, readConfig, err
+	}
+	outputLoc, err := loadLocation(config.Output.Location)
+	if err != nil {
+		return csvReader, csvWriter, readConfig, err
+	}
+	csvReader = CsvReader{DataPath: config.Source.DataPath, FileNamePattern: config.Source.FileNamePattern, DateFormat: config.Source.DateFormat,
+		PricePrecision: config.Source.PricePrecision, SymbolPrecision: config.Source.SymbolPrecision, DetectPrecision: config.Source.DetectPrecision, Location: sourceLoc}
+	csvWriter = CsvWriter{OutputPath: config.Output.OutputPath, FileNamePattern: config.Output.FileNamePattern, DateFormat: config.Output.DateFormat, Location: outputLoc}
+	if config.Start != "" {
+		if readConfig.Range.StartDate, err = parseDate(config.Source.DateFormat, config.Start, sourceLoc); err != nil {
+			return csvReader, csvWriter, readConfig, err
+		}
+	}
+	if config.End != "" {
+		readConfig.Range.EndDate, err = parseDate(config.Source.DateFormat, config.End, sourceLoc)
+	}
+	return csvReader, csvWriter, readConfig, err
+}
+
+func (config *PipelineConfig) runSymbol(symbol string, csvReader CsvReader, csvWriter CsvWriter, readConfig ReadConfig) (int, error) {
+	data, err := ReadTickerData(csvReader, &TickerForRead{Symbol: symbol, Config: []ReadConfig{readConfig}})
+	if err != nil {
+		return 0, err
+	}
+	td := data[config.BaseTimeFrame]
+	td.HigherTfIds = nil
+	addFields := []string{"id"}
+	higherTfs := []string{}
+	ticker := TickerForWrite{Symbol: symbol, BaseTimeFrame: config.BaseTimeFrame}
+	for _, timeFrame := range config.TimeFrames {
+		if timeFrame != config.BaseTimeFrame {
+			addFields = append(addFields, timeFrame+"_id")
+			higherTfs = append(higherTfs, timeFrame)
+		}
+		ticker.Config = append(ticker.Config, WriteConfig{TimeFrame: timeFrame, Append: config.Append, IncludeIncomplete: config.IncludeIncomplete})
+	}
+	processedTd := ProcessRawTickerData(td, &TickerSplitData{}, config.BaseTimeFrame, addFields, higherTfs)
+	ca, err := config.readCorporateActions(symbol, csvReader)
+	if err != nil {
+		return 0, err
+	}
+	if ca != nil {
+		processedTd.AdjustForCorporateActions(ca)
+	}
+	return len(processedTd.Date), WriteTickerData(csvWriter, &processedTd, &ticker)
+}
+
+func (config *PipelineConfig) readCorporateActions(symbol string, csvReader CsvReader) (*CorporateActions, error) {
+	adjustments := config.Adjustments
+	if adjustments.SplitFileNamePattern == "" && adjustments.DividendFileNamePattern == "" && adjustments.YahooFileNamePattern == "" {
+		return nil, nil
+	}
+	if adjustments.ActionDateFormat != "" {
+		csvReader.DateFormat = adjustments.ActionDateFormat
+	}
+	ca := CorporateActions{Symbol: symbol}
+	if adjustments.YahooFileNamePattern != "" {
+		csvReader.FileNamePattern = adjustments.YahooFileNamePattern
+		yahooCa, err := ReadCorporateActions(csvReader, symbol, YAHOO)
+		if err != nil {
+			return nil, err
+		}
+		ca.Merge(yahooCa.Actions)
+	}
+	if adjustments.SplitFileNamePattern != "" {
+		csvReader.FileNamePattern = adjustments.SplitFileNamePattern
+		tsd, err := ReadSplitData(csvReader, symbol, OTHER)
+		if err != nil {
+			return nil, err
+		}
+		splitCa := NewCorporateActions(symbol, &tsd, nil)
+		ca.Merge(splitCa.Actions)
+	}
+	if adjustments.DividendFileNamePattern != "" {
+		csvReader.FileNamePattern = adjustments.DividendFileNamePattern
+		tdd, err := ReadDividendData(csvReader, symbol, OTHER)
+		if err != nil {
+			return nil, err
+		}
+		dividendCa := NewCorporateActions(symbol, nil, &tdd)
+		ca.Merge(dividendCa.Actions)
+	}
+	return &ca, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(name)
+}
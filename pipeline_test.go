@@ -0,0 +1,79 @@
+package marketdata
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunPipeline(t *testing.T) {
+	outputPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker" + string(os.PathSeparator) + "processed" + string(os.PathSeparator)
+	configJson := `{
+		"source": {"data_path": "./testdata/ticker", "file_name_pattern": "{ticker}-{timeframe}.csv", "date_format": "1/2/2006"},
+		"output": {"output_path": "` + outputPath + `", "file_name_pattern": "{ticker}-pipeline-{timeframe}.csv", "date_format": "2006-01-02"},
+		"symbols": ["someticker", "missing"],
+		"base_timeframe": "daily",
+		"timeframes": ["daily", "weekly"],
+		"start": "12/8/2016",
+		"include_incomplete": true,
+		"adjustments": {"split_file_name_pattern": "{ticker}-recentsplitdata.csv", "action_date_format": "20060102"}
+	}`
+	config, err := ReadPipelineConfig(strings.NewReader(configJson))
+	if err != nil {
+		t.Log("TestRunPipeline failed to read the config. Error is: ", err)
+		t.FailNow()
+	}
+	statuses := RunPipeline(&config)
+	if len(statuses) != 2 || statuses[0].Err != nil || statuses[0].Bars != 2 || statuses[1].Symbol != "missing" || statuses[1].Err == nil {
+		t.Log("TestRunPipeline failed. Result was: ", statuses)
+		t.Fail()
+	}
+	expectedFiles := map[string]string{
+		"someticker-pipeline-daily.csv":  "id,weekly_id,date,open,high,low,close,volume\n0,-1,2016-12-08,68.13,69.11,67.9,69.02,95588800\n1,-1,2016-12-09,138.39,138.82,137.75,138.3,34276600\n",
+		"someticker-pipeline-weekly.csv": "id,date,open,high,low,close,volume,bar_count,incomplete\n0,2016-12-08,68.13,138.82,67.9,138.3,129865400,2,false\n",
+	}
+	for fileName, expectedValue := range expectedFiles {
+		result, _ := ioutil.ReadFile(outputPath + fileName)
+		if string(result) != expectedValue {
+			t.Log("TestRunPipeline failed to write ", fileName, ". Result was: ", string(result), " but should be: ", expectedValue)
+			t.Fail()
+		}
+		os.Remove(outputPath + fileName)
+	}
+}
+
+func TestReadPipelineConfigFailures(t *testing.T) {
+	testCases := []string{
+		`{"symbols": ["someticker"], "base_timeframe": "daily", "timeframes": ["daily"], "unknown": true}`,
+		`{"symbols": [], "base_timeframe": "daily", "timeframes": ["daily"]}`,
+		`{"symbols": ["someticker"`,
+	}
+	for _, configJson := range testCases {
+		if _, err := ReadPipelineConfig(strings.NewReader(configJson)); err == nil {
+			t.Log("TestReadPipelineConfigFailures should fail for config: ", configJson)
+			t.Fail()
+		}
+	}
+}
+
+func TestPipelineMergesCorporateActions(t *testing.T) {
+	var config PipelineConfig
+	config.Adjustments = PipelineAdjustments{YahooFileNamePattern: "{ticker}-yahoosplitdividend.csv", SplitFileNamePattern: "{ticker}-recentsplitdata.csv", ActionDateFormat: "20060102"}
+	csvReader := CsvReader{DataPath: "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker", DateFormat: "1/2/2006"}
+	ca, err := config.readCorporateActions("someticker", csvReader)
+	splits, dividends := 0, 0
+	if ca != nil {
+		for _, action := range ca.Actions {
+			if action.Type == SPLIT {
+				splits++
+			} else if action.Type == DIVIDEND {
+				dividends++
+			}
+		}
+	}
+	if err != nil || splits != 3 || dividends != 4 {
+		t.Log("TestPipelineMergesCorporateActions failed. Splits were: ", splits, " and dividends: ", dividends, " but should be: 3 and 4 Error: ", err)
+		t.Fail()
+	}
+}
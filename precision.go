@@ -0,0 +1,108 @@
+package marketdata
+
+import (
+	"math"
+)
+
+const (
+	defaultPricePrecision = 2
+	maxPricePrecision     = 8
+)
+
+// FixedPrice stores a price as an integer number of ticks at a given decimal precision,
+// so that sums and averages over many bars do not accumulate floating point drift.
+type FixedPrice int64
+
+func ToFixedPrice(v float64, precision int) FixedPrice {
+	return FixedPrice(round(v * math.Pow(10, float64(precision))))
+}
+
+func (p FixedPrice) Float64(precision int) float64 {
+	return roundPlus(float64(p)/math.Pow(10, float64(precision)), precision)
+}
+
+func (td *TickerData) FixedPrices(field string) []FixedPrice {
+	var values []float64
+	switch field {
+	case "open":
+		values = td.Open
+	case "high":
+		values = td.High
+	case "low":
+		values = td.Low
+	case "close":
+		values = td.Close
+	}
+	dp := td.pricePrecision()
+	prices := make([]FixedPrice, len(values))
+	for i, value := range values {
+		prices[i] = ToFixedPrice(value, dp)
+	}
+	return prices
+}
+
+func (td *TickerData) RoundPrices() {
+	dp := td.pricePrecision()
+	l := len(td.Date)
+	for i := 0; i < l; i++ {
+		if td.Open != nil {
+			td.Open[i] = roundPlus(td.Open[i], dp)
+		}
+		if td.High != nil {
+			td.High[i] = roundPlus(td.High[i], dp)
+		}
+		if td.Low != nil {
+			td.Low[i] = roundPlus(td.Low[i], dp)
+		}
+		if td.Close != nil {
+			td.Close[i] = roundPlus(td.Close[i], dp)
+		}
+	}
+}
+
+// NewPrecision returns a precision of places decimals for the Precision field of
+// TickerData and the PricePrecision field of the readers.
+func NewPrecision(places int) *int {
+	return &places
+}
+
+// pricePrecision returns the configured number of decimal places, 2 when Precision is
+// not set.
+func (td *TickerData) pricePrecision() int {
+	if td.Precision != nil {
+		return *td.Precision
+	}
+	return defaultPricePrecision
+}
+
+// resolvePricePrecision returns the precision of symbol set per symbol or per reader.
+// Without either it is detected from the raw data just read when detect is set and
+// otherwise left unset, which means 2 decimals.
+func resolvePricePrecision(td *TickerData, symbol string, precision *int, symbolPrecision map[string]int, detect bool) *int {
+	if places, exists := symbolPrecision[symbol]; exists {
+		return NewPrecision(places)
+	}
+	if precision != nil {
+		return NewPrecision(*precision)
+	}
+	if detect {
+		return NewPrecision(detectPricePrecision(td))
+	}
+	return nil
+}
+
+func detectPricePrecision(td *TickerData) int {
+	dp := 0
+	for _, prices := range [][]float64{td.Open, td.High, td.Low, td.Close} {
+		for _, price := range prices {
+			places := numDecimalPlaces(price)
+			if places > dp {
+				dp = places
+			}
+			if dp >= maxPricePrecision {
+				return maxPricePrecision
+			}
+		}
+	}
+	return dp
+}
@@ -0,0 +1,107 @@
+package marketdata
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPricePrecision(t *testing.T) {
+	testCases := []struct {
+		name          string
+		close         []float64
+		precision     *int
+		expectedValue int
+	}{
+		{"'Default precision'", []float64{1.05123, 1.0512}, nil, 2},
+		{"'Use configured precision'", []float64{226.27}, NewPrecision(4), 4},
+		{"'Use configured zero precision'", []float64{226.27}, NewPrecision(0), 0},
+	}
+	for _, tc := range testCases {
+		var td TickerData
+		td.Close = tc.close
+		td.Precision = tc.precision
+		result := td.pricePrecision()
+		if result != tc.expectedValue {
+			t.Log("TestPricePrecision test case ", tc.name, " failed. Result was: ", result, " but should be: ", tc.expectedValue)
+			t.Fail()
+		}
+	}
+}
+
+func TestDetectPricePrecision(t *testing.T) {
+	testCases := []struct {
+		name          string
+		close         []float64
+		expectedValue int
+	}{
+		{"'Detect precision of equity prices'", []float64{226.27, 226.1, 225}, 2},
+		{"'Detect precision of fx prices'", []float64{1.05123, 1.0512}, 5},
+		{"'Detect precision of whole prices'", []float64{99, 100}, 0},
+		{"'Cap detected precision'", []float64{226.27 / 3}, maxPricePrecision},
+	}
+	for _, tc := range testCases {
+		var td TickerData
+		td.Close = tc.close
+		result := detectPricePrecision(&td)
+		if result != tc.expectedValue {
+			t.Log("TestDetectPricePrecision test case ", tc.name, " failed. Result was: ", result, " but should be: ", tc.expectedValue)
+			t.Fail()
+		}
+	}
+}
+
+func TestAdjustTickerDataForSplitsWithWholePrices(t *testing.T) {
+	var td TickerData
+	td.Date = createDates([]string{"1/3/2017", "1/4/2017"}, "1/2/2006")
+	td.Open = []float64{100, 50}
+	td.High = []float64{101, 50}
+	td.Low = []float64{99, 50}
+	td.Close = []float64{100, 50}
+	td.Volume = []int64{1000, 2000}
+	var tsd TickerSplitData
+	tsd.Date = []time.Time{td.Date[1]}
+	tsd.BeforeSplitQty = []int{1}
+	tsd.AfterSplitQty = []int{2}
+	td.AdjustTickerDataForSplits(&tsd)
+	if td.Low[0] != 49.5 || td.High[0] != 50.5 {
+		t.Log("TestAdjustTickerDataForSplitsWithWholePrices failed. Result was: ", td.Low[0], td.High[0], " but should be: 49.5 50.5")
+		t.Fail()
+	}
+}
+
+func TestAdjustTickerDataForSplitsUsesPrecision(t *testing.T) {
+	var td TickerData
+	td.Date = createDates([]string{"1/3/2017", "1/4/2017"}, "1/2/2006")
+	td.Open = []float64{0.00012345, 0.00006}
+	td.High = []float64{0.00012355, 0.00006}
+	td.Low = []float64{0.00012335, 0.00006}
+	td.Close = []float64{0.00012347, 0.00006}
+	td.Volume = []int64{1000, 2000}
+	td.Precision = NewPrecision(8)
+	var tsd TickerSplitData
+	tsd.Date = []time.Time{td.Date[1]}
+	tsd.BeforeSplitQty = []int{1}
+	tsd.AfterSplitQty = []int{3}
+	td.AdjustTickerDataForSplits(&tsd)
+	expectedOpen := []float64{0.00004115, 0.00006}
+	expectedClose := []float64{0.00004116, 0.00006}
+	if !reflect.DeepEqual(td.Open, expectedOpen) || !reflect.DeepEqual(td.Close, expectedClose) {
+		t.Log("TestAdjustTickerDataForSplitsUsesPrecision failed. Result was: ", td.Open, td.Close, " but should be: ", expectedOpen, expectedClose)
+		t.Fail()
+	}
+}
+
+func TestFixedPrice(t *testing.T) {
+	var td TickerData
+	td.Precision = NewPrecision(1)
+	td.Close = []float64{0.1, 0.2, 0.7}
+	var sum FixedPrice
+	for _, price := range td.FixedPrices("close") {
+		sum = sum + price
+	}
+	if sum.Float64(1) != 1.0 || ToFixedPrice(1.05123, 5) != 105123 {
+		t.Log("TestFixedPrice failed. Sum was: ", sum.Float64(1), " but should be: 1")
+		t.Fail()
+	}
+}
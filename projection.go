@@ -0,0 +1,38 @@
+package marketdata
+
+import (
+	"errors"
+	"math"
+)
+
+// ProjectHigherTimeFrame maps values computed on the bars of higherTimeFrame, such as
+// the bars created by Resample or an indicator over them, onto the bars of td.
+// Every bar receives the value of the last higher time frame bar that was complete at
+// its close, which is the bar of its own period for the last bar of that period and the
+// bar of the previous period otherwise. As the period of the final bar of td may still
+// be in progress, it is only complete on the last trading day of the period. With includeInProgress every bar
+// receives the value of the period it is part of instead. Bars without a value are NaN.
+func ProjectHigherTimeFrame(td *TickerData, higherTimeFrame string, values []float64, includeInProgress bool) ([]float64, error) {
+	ids, ok := td.HigherTfIds[higherTimeFrame+"_id"]
+	if !ok {
+		return nil, errors.New("Field " + higherTimeFrame + " does not exist in ticker data.")
+	}
+	cal := td.calendar()
+	result := make([]float64, len(ids))
+	for i, id := range ids {
+		// A bar with id n is part of the higher time frame bar at index n+1.
+		index := int(id)
+		lastOfPeriod := i+1 < len(ids) && ids[i+1] != id
+		if i+1 == len(ids) {
+			lastOfPeriod = cal.isLastDayOfTimeFrame(td.Date[i], higherTimeFrame)
+		}
+		if includeInProgress || lastOfPeriod {
+			index++
+		}
+		result[i] = math.NaN()
+		if index >= 0 && index < len(values) {
+			result[i] = values[index]
+		}
+	}
+	return result, nil
+}
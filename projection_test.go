@@ -0,0 +1,51 @@
+package marketdata
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProjectHigherTimeFrame(t *testing.T) {
+	nan := math.NaN()
+	testCases := []struct {
+		name              string
+		includeInProgress bool
+		expectedResult    []float64
+	}{
+		{"'Last completed weekly bar'", false, []float64{nan, nan, nan, nan, 10, 10, 10, 10, 10, 20, 20, 20}},
+		{"'Including the weekly bar in progress'", true, []float64{10, 10, 10, 10, 10, 20, 20, 20, 20, 20, 30, 30}},
+	}
+	td := getExpectedDailyDataWithWeeklyAndMonthlyIds()
+	for _, tc := range testCases {
+		result, err := ProjectHigherTimeFrame(&td, "weekly", []float64{10, 20, 30, 40}, tc.includeInProgress)
+		if err != nil || !approxEqualSlice(result[:12], tc.expectedResult) {
+			t.Log("TestProjectHigherTimeFrame test case ", tc.name, " failed. Result was: ", result, " but should be: ", tc.expectedResult, " Error: ", err)
+			t.Fail()
+		}
+	}
+	weekly, err := Resample(&td, "weekly")
+	result, _ := ProjectHigherTimeFrame(&td, "weekly", weekly.Close, false)
+	expectedResult := []float64{nan, nan, nan, nan, 219.68, 219.68, 219.68, 219.68, 219.68, 226.51, 226.51, 226.51}
+	if err != nil || !approxEqualSlice(result[:12], expectedResult) {
+		t.Log("TestProjectHigherTimeFrame failed to project resampled bars. Result was: ", result, " but should be: ", expectedResult, " Error: ", err)
+		t.Fail()
+	}
+	var hourly TickerData
+	for _, day := range []int{3, 4, 5} {
+		for hour := 10; hour <= 12; hour++ {
+			hourly.Date = append(hourly.Date, time.Date(2017, 1, day, hour, 0, 0, 0, time.UTC))
+		}
+	}
+	hourly.HigherTfIds = map[string][]int32{"daily_id": {-1, -1, -1, 0, 0, 0, 1, 1, 1}}
+	result, err = ProjectHigherTimeFrame(&hourly, "daily", []float64{102, 112, 122}, false)
+	expectedResult = []float64{nan, nan, 102, 102, 102, 112, 112, 112, 122}
+	if err != nil || !approxEqualSlice(result, expectedResult) {
+		t.Log("TestProjectHigherTimeFrame failed for intraday bars. Result was: ", result, " but should be: ", expectedResult, " Error: ", err)
+		t.Fail()
+	}
+	if _, err := ProjectHigherTimeFrame(&td, "quarterly", []float64{10}, false); err == nil {
+		t.Log("TestProjectHigherTimeFrame should fail for a missing higher time frame.")
+		t.Fail()
+	}
+}
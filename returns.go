@@ -0,0 +1,118 @@
+package marketdata
+
+import "math"
+
+// Returns computes simple close-to-close returns. When ca is given the prices are first
+// brought to the fully adjusted state of ca, using the AdjFactor column to undo any
+// adjustment already applied, so splits and dividends do not show up as returns. With
+// a nil ca the prices are used as stored. The first value is NaN.
+func (td *TickerData) Returns(ca *CorporateActions) []float64 {
+	close := td.adjustedPrices(td.Close, ca)
+	returns := make([]float64, len(close))
+	for i := range close {
+		if i == 0 {
+			returns[i] = math.NaN()
+			continue
+		}
+		returns[i] = close[i]/close[i-1] - 1
+	}
+	return returns
+}
+
+func (td *TickerData) LogReturns(ca *CorporateActions) []float64 {
+	returns := td.Returns(ca)
+	for i := range returns {
+		returns[i] = math.Log1p(returns[i])
+	}
+	return returns
+}
+
+func (td *TickerData) OpenToCloseReturns() []float64 {
+	returns := make([]float64, len(td.Close))
+	for i := range td.Close {
+		returns[i] = td.Close[i]/td.Open[i] - 1
+	}
+	return returns
+}
+
+// OvernightReturns computes the gap from the previous close to the open of each bar.
+func (td *TickerData) OvernightReturns(ca *CorporateActions) []float64 {
+	open := td.adjustedPrices(td.Open, ca)
+	close := td.adjustedPrices(td.Close, ca)
+	returns := make([]float64, len(open))
+	for i := range open {
+		if i == 0 {
+			returns[i] = math.NaN()
+			continue
+		}
+		returns[i] = open[i]/close[i-1] - 1
+	}
+	return returns
+}
+
+func (td *TickerData) adjustedPrices(prices []float64, ca *CorporateActions) []float64 {
+	adjusted := make([]float64, len(prices))
+	copy(adjusted, prices)
+	if ca == nil {
+		return adjusted
+	}
+	// Dividend factors depend on the raw close, so they are computed on the unadjusted
+	// closes of stored adjusted data.
+	raw := td
+	if td.AdjFactor != nil {
+		raw = &TickerData{Date: td.Date, Close: make([]float64, len(td.Close))}
+		for i := range td.Close {
+			raw.Close[i] = td.Close[i]
+			if td.AdjFactor[i] != 0 {
+				raw.Close[i] = td.Close[i] / td.AdjFactor[i]
+			}
+		}
+	}
+	priceFactors, _ := ca.AdjustmentFactors(raw)
+	for i := range adjusted {
+		if td.AdjFactor != nil && td.AdjFactor[i] != 0 {
+			adjusted[i] = adjusted[i] / td.AdjFactor[i]
+		}
+		adjusted[i] = adjusted[i] * priceFactors[i]
+	}
+	return adjusted
+}
+
+// CumulativeReturns compounds returns into a return curve. NaN returns count as zero.
+func CumulativeReturns(returns []float64) []float64 {
+	cumulative := make([]float64, len(returns))
+	growth := 1.0
+	for i, r := range returns {
+		if !math.IsNaN(r) {
+			growth = growth * (1 + r)
+		}
+		cumulative[i] = growth - 1
+	}
+	return cumulative
+}
+
+// Drawdowns returns the decline of the return curve from its running peak as a
+// negative fraction, or zero at a new peak.
+func Drawdowns(returns []float64) []float64 {
+	cumulative := CumulativeReturns(returns)
+	drawdowns := make([]float64, len(cumulative))
+	peak := 1.0
+	for i, c := range cumulative {
+		growth := 1 + c
+		if growth > peak {
+			peak = growth
+		}
+		drawdowns[i] = growth/peak - 1
+	}
+	return drawdowns
+}
+
+func MaxDrawdown(returns []float64) float64 {
+	maxDrawdown := 0.0
+	for _, drawdown := range Drawdowns(returns) {
+		if drawdown < maxDrawdown {
+			maxDrawdown = drawdown
+		}
+	}
+	return maxDrawdown
+}
@@ -0,0 +1,80 @@
+package marketdata
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReturns(t *testing.T) {
+	var td TickerData
+	td.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017", "1/6/2017"}, "1/2/2006")
+	td.Open = []float64{100, 108, 100, 118}
+	td.Close = []float64{100, 110, 99, 120}
+	testCases := []struct {
+		name           string
+		result         []float64
+		expectedResult []float64
+	}{
+		{"'Close to close returns'", td.Returns(nil), []float64{math.NaN(), 0.1, -0.1, 120.0/99 - 1}},
+		{"'Log returns'", td.LogReturns(nil), []float64{math.NaN(), math.Log(1.1), math.Log(0.9), math.Log(120.0 / 99)}},
+		{"'Open to close returns'", td.OpenToCloseReturns(), []float64{0, 110.0/108 - 1, -0.01, 120.0/118 - 1}},
+		{"'Overnight returns'", td.OvernightReturns(nil), []float64{math.NaN(), 0.08, 100.0/110 - 1, 118.0/99 - 1}},
+		{"'Cumulative returns'", CumulativeReturns(td.Returns(nil)), []float64{0, 0.1, -0.01, 0.2}},
+		{"'Drawdowns'", Drawdowns(td.Returns(nil)), []float64{0, 0, -0.1, 0}},
+	}
+	for _, tc := range testCases {
+		if !approxEqualSlice(tc.result, tc.expectedResult) {
+			t.Log("TestReturns test case ", tc.name, " failed. Result was: ", tc.result, " but should be: ", tc.expectedResult)
+			t.Fail()
+		}
+	}
+	if math.Abs(MaxDrawdown(td.Returns(nil))+0.1) > 1e-9 {
+		t.Log("TestReturns failed to compute the maximum drawdown. Result was: ", MaxDrawdown(td.Returns(nil)))
+		t.Fail()
+	}
+}
+
+func TestReturnsAcrossSplit(t *testing.T) {
+	var raw TickerData
+	raw.Date = createDates([]string{"1/3/2017", "1/4/2017"}, "1/2/2006")
+	raw.Close = []float64{100, 50}
+	ca := CorporateActions{Symbol: "someticker"}
+	ca.Add(CorporateAction{Date: raw.Date[1], Type: SPLIT, BeforeQty: 1, AfterQty: 2})
+	adjusted := raw
+	adjusted.Close = []float64{50, 50}
+	adjusted.AdjFactor = []float64{0.5, 1}
+	for _, td := range []TickerData{raw, adjusted} {
+		result := td.Returns(&ca)
+		if math.Abs(result[1]) > 1e-9 {
+			t.Log("TestReturnsAcrossSplit failed. Result was: ", result, " but the split should not produce a return.")
+			t.Fail()
+		}
+	}
+	raw.Date = createDates([]string{"1/3/2017", "1/4/2017", "1/5/2017"}, "1/2/2006")
+	raw.Close = []float64{100, 100, 50}
+	ca = CorporateActions{Symbol: "someticker"}
+	ca.Add(CorporateAction{Date: raw.Date[1], Type: DIVIDEND, Amount: 10})
+	ca.Add(CorporateAction{Date: raw.Date[2], Type: SPLIT, BeforeQty: 1, AfterQty: 2})
+	adjusted = raw
+	adjusted.Close = []float64{45, 50, 50}
+	adjusted.AdjFactor = []float64{0.45, 0.5, 1}
+	expectedResult := []float64{math.NaN(), 1.0 / 9, 0}
+	for _, td := range []TickerData{raw, adjusted} {
+		if result := td.Returns(&ca); !approxEqualSlice(result, expectedResult) {
+			t.Log("TestReturnsAcrossSplit failed for a dividend before a split. Result was: ", result, " but should be: ", expectedResult)
+			t.Fail()
+		}
+	}
+}
+
+func approxEqualSlice(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) != math.IsNaN(b[i]) || (!math.IsNaN(a[i]) && math.Abs(a[i]-b[i]) > 1e-9) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,305 @@
+package marketdata
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const apiDateFormat = "2006-01-02"
+
+// Server exposes ticker data, splits, dividends and events over HTTP:
+//
+//	/bars/{symbol}?tf=weekly&start=2017-01-02&end=2017-03-31
+//	/splits/{symbol}
+//	/dividends/{symbol}
+//	/events/{name}
+//
+// Every endpoint returns JSON unless format=csv is given. Dates use the format
+// 2006-01-02 and the start and end of a range are inclusive. Endpoints whose reader
+// is nil or has no data respond with 404 and invalid query parameters with 400, while
+// errors of the stored data respond with 500. Responses carry an ETag and, when the
+// reader can report modification times like CsvReader, a Last-Modified header. As
+// RFC 7232 requires, If-Modified-Since is only used without If-None-Match.
+type Server struct {
+	TickerReader   DataReader
+	SplitReader    DataReader
+	DividendReader DataReader
+	EventReader    DataReader
+	Source         DataSource
+}
+
+type modTimeReader interface {
+	modTime(kind string, name string, timeFrame string) (time.Time, error)
+}
+
+// badRequestError is the error of an invalid query parameter.
+type badRequestError struct {
+	message string
+}
+
+func (e badRequestError) Error() string {
+	return e.message
+}
+
+type barJson struct {
+	Id           *int32   `json:"id,omitempty"`
+	Date         string   `json:"date"`
+	Open         float64  `json:"open"`
+	High         float64  `json:"high"`
+	Low          float64  `json:"low"`
+	Close        float64  `json:"close"`
+	Volume       int64    `json:"volume"`
+	OpenInterest *int64   `json:"open_interest,omitempty"`
+	AdjFactor    *float64 `json:"adj_factor,omitempty"`
+	Vwap         *float64 `json:"vwap,omitempty"`
+}
+
+type splitJson struct {
+	Date   string `json:"date"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+type dividendJson struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	endpoint, name := parts[0], parts[1]
+	query := r.URL.Query()
+	csv := query.Get("format") == "csv"
+	var reader DataReader
+	var kind, timeFrame string
+	switch endpoint {
+	case "bars":
+		reader, kind, timeFrame = server.TickerReader, "ticker", query.Get("tf")
+		if timeFrame == "" {
+			timeFrame = "daily"
+		}
+	case "splits":
+		reader, kind = server.SplitReader, "split"
+	case "dividends":
+		reader, kind = server.DividendReader, "dividend"
+	case "events":
+		reader, kind = server.EventReader, "event"
+	}
+	if reader == nil {
+		http.NotFound(w, r)
+		return
+	}
+	modTime := time.Time{}
+	if mtr, ok := reader.(modTimeReader); ok {
+		modTime, _ = mtr.modTime(kind, name, timeFrame)
+	}
+	var body []byte
+	var err error
+	switch kind {
+	case "ticker":
+		body, err = server.bars(reader, name, timeFrame, query.Get("start"), query.Get("end"), csv)
+	case "split":
+		body, err = server.splits(reader, name, csv)
+	case "dividend":
+		body, err = server.dividends(reader, name, csv)
+	case "event":
+		body, err = server.events(reader, name, csv)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	hash := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(hash[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if csv {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(body)
+}
+
+func errorStatus(err error) int {
+	var notFound notFoundError
+	var badRequest badRequestError
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &badRequest):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// notModified reports whether the client already has the response with etag.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modTime.IsZero() && !modTime.Truncate(time.Second).After(since)
+}
+
+func (server *Server) bars(reader DataReader, symbol string, timeFrame string, start string, end string, csv bool) ([]byte, error) {
+	loc := time.UTC
+	if lr, ok := reader.(locatedReader); ok && lr.getLocation() != nil {
+		loc = lr.getLocation()
+	}
+	startDate, endDate, err := parseApiRange(start, end, loc)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ReadTickerData(reader, &TickerForRead{Symbol: symbol, Config: []ReadConfig{{TimeFrame: timeFrame}}})
+	if err != nil {
+		return nil, err
+	}
+	td := createSortedTickerData(data[timeFrame], []string{})
+	begin, stop := 0, len(td.Date)
+	for begin < stop && !startDate.IsZero() && td.Date[begin].Before(startDate) {
+		begin++
+	}
+	for stop > begin && !endDate.IsZero() && td.Date[stop-1].After(endDate) {
+		stop--
+	}
+	td = copyTickerDataRange(&td, begin, stop)
+	var buffer bytes.Buffer
+	if csv {
+		writer := bufio.NewWriter(&buffer)
+		sortedHigherTfIds := getSortedHigherTimeFrameIds(td.HigherTfIds)
+		printHeader(writer, &td, sortedHigherTfIds, "\n")
+		printTickerData(writer, &td, sortedHigherTfIds, 0, "\n", apiDateFormat, nil)
+		writer.Flush()
+		return buffer.Bytes(), nil
+	}
+	bars := make([]barJson, len(td.Date))
+	for i := range td.Date {
+		bars[i] = barJson{Date: td.Date[i].Format(apiDateFormat), Open: td.Open[i], High: td.High[i], Low: td.Low[i], Close: td.Close[i]}
+		if td.Volume != nil {
+			bars[i].Volume = td.Volume[i]
+		}
+		if td.Id != nil {
+			bars[i].Id = &td.Id[i]
+		}
+		if td.OpenInterest != nil {
+			bars[i].OpenInterest = &td.OpenInterest[i]
+		}
+		if td.AdjFactor != nil {
+			bars[i].AdjFactor = &td.AdjFactor[i]
+		}
+		if td.Vwap != nil {
+			bars[i].Vwap = &td.Vwap[i]
+		}
+	}
+	return json.Marshal(bars)
+}
+
+func (server *Server) splits(reader DataReader, symbol string, csv bool) ([]byte, error) {
+	tsd, err := ReadSplitData(reader, symbol, server.Source)
+	if err != nil {
+		return nil, err
+	}
+	if csv {
+		body := "date,split\n"
+		for i := range tsd.Date {
+			body = body + fmt.Sprintf("%s,%d:%d\n", tsd.Date[i].Format(apiDateFormat), tsd.AfterSplitQty[i], tsd.BeforeSplitQty[i])
+		}
+		return []byte(body), nil
+	}
+	splits := make([]splitJson, len(tsd.Date))
+	for i := range tsd.Date {
+		splits[i] = splitJson{tsd.Date[i].Format(apiDateFormat), tsd.BeforeSplitQty[i], tsd.AfterSplitQty[i]}
+	}
+	return json.Marshal(splits)
+}
+
+func (server *Server) dividends(reader DataReader, symbol string, csv bool) ([]byte, error) {
+	tdd, err := ReadDividendData(reader, symbol, server.Source)
+	if err != nil {
+		return nil, err
+	}
+	if csv {
+		body := "date,dividend\n"
+		for i := range tdd.Date {
+			body = body + tdd.Date[i].Format(apiDateFormat) + "," + formatDividend(&tdd, i) + "\n"
+		}
+		return []byte(body), nil
+	}
+	dividends := make([]dividendJson, len(tdd.Date))
+	for i := range tdd.Date {
+		dividends[i] = dividendJson{tdd.Date[i].Format(apiDateFormat), tdd.Amount[i]}
+	}
+	return json.Marshal(dividends)
+}
+
+func (server *Server) events(reader DataReader, name string, csv bool) ([]byte, error) {
+	eventData, err := ReadEventData(reader, &Event{name})
+	if err != nil {
+		return nil, err
+	}
+	dates := []string{}
+	for _, date := range getSortedEventDates(&eventData) {
+		dates = append(dates, date.Format(apiDateFormat))
+	}
+	if csv {
+		return []byte("date\n" + strings.Join(append(dates, ""), "\n")), nil
+	}
+	return json.Marshal(dates)
+}
+
+// parseApiRange parses the range in loc, the location of the bars.
+func parseApiRange(start string, end string, loc *time.Location) (time.Time, time.Time, error) {
+	var startDate, endDate time.Time
+	var err error
+	if start != "" {
+		if startDate, err = time.ParseInLocation(apiDateFormat, start, loc); err != nil {
+			return startDate, endDate, badRequestError{fmt.Sprintf("Invalid start date %s.", start)}
+		}
+	}
+	if end != "" {
+		if endDate, err = time.ParseInLocation(apiDateFormat, end, loc); err != nil {
+			return startDate, endDate, badRequestError{fmt.Sprintf("Invalid end date %s.", end)}
+		}
+		// The whole end day is part of the range.
+		endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return startDate, endDate, nil
+}
+
+func (csvReader CsvReader) modTime(kind string, name string, timeFrame string) (time.Time, error) {
+	info, err := os.Stat(csvReader.sourceFileName(kind, name, timeFrame))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
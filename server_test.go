@@ -0,0 +1,119 @@
+package marketdata
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getTestServer() *Server {
+	tickerPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "ticker"
+	eventPath := "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "event"
+	return &Server{
+		TickerReader:   CsvReader{DataPath: tickerPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"},
+		SplitReader:    CsvReader{DataPath: tickerPath, FileNamePattern: "{ticker}-splitdata.csv", DateFormat: "20060102"},
+		DividendReader: CsvReader{DataPath: tickerPath, FileNamePattern: "{ticker}-dividenddata.csv", DateFormat: "20060102"},
+		EventReader:    CsvReader{DataPath: eventPath, FileNamePattern: "{eventname}.csv", DateFormat: "1/2/2006"},
+		Source:         OTHER,
+	}
+}
+
+func TestServer(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedPrefix string
+	}{
+		{"'Bars in a date range'", "/bars/someticker?start=2016-12-08&end=2016-12-08", http.StatusOK, `[{"id":1,"date":"2016-12-08","open":136.25,`},
+		{"'Bars as csv'", "/bars/someticker?format=csv", http.StatusOK, "id,monthly_id,weekly_id,date,open,high,low,close,volume\n0,-1,-1,2016-12-07,"},
+		{"'Splits'", "/splits/someticker", http.StatusOK, `[{"date":"2002-06-05","before":2,"after":3},{"date":"2005-06-09","before":1,"after":2}]`},
+		{"'Dividends as csv'", "/dividends/someticker?format=csv", http.StatusOK, "date,dividend\n"},
+		{"'Events'", "/events/testevent", http.StatusOK, `["2000-05-26","2000-07-11"`},
+		{"'Missing symbol'", "/bars/missing", http.StatusNotFound, ""},
+		{"'Invalid start date'", "/bars/someticker?start=12/8/2016", http.StatusBadRequest, ""},
+		{"'Unknown endpoint'", "/quotes/someticker", http.StatusNotFound, ""},
+	}
+	server := getTestServer()
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("GET", tc.url, nil))
+		if recorder.Code != tc.expectedStatus || !strings.HasPrefix(recorder.Body.String(), tc.expectedPrefix) {
+			t.Log("TestServer test case ", tc.name, " failed. Result was: ", recorder.Code, " ", recorder.Body.String(), " but should be: ", tc.expectedStatus, " ", tc.expectedPrefix)
+			t.Fail()
+		}
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/bars/someticker?tf=daily", nil))
+	var bars []map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &bars); err != nil || len(bars) != 3 {
+		t.Log("TestServer failed to return valid JSON. Result was: ", recorder.Body.String(), " Error: ", err)
+		t.Fail()
+	}
+	dataPath := t.TempDir()
+	ioutil.WriteFile(dataPath+string(os.PathSeparator)+"broken-daily.csv", []byte{}, 0644)
+	server.TickerReader = CsvReader{DataPath: dataPath, FileNamePattern: "{ticker}-{timeframe}.csv", DateFormat: "1/2/2006"}
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/bars/broken", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Log("TestServer should respond with 500 for a malformed stored file. Result was: ", recorder.Code, " ", recorder.Body.String())
+		t.Fail()
+	}
+}
+
+func TestServerRangeInReaderLocation(t *testing.T) {
+	server := getTestServer()
+	for _, loc := range []*time.Location{time.FixedZone("JST", 9*3600), time.FixedZone("EST", -5*3600)} {
+		reader := server.TickerReader.(CsvReader)
+		reader.Location = loc
+		server.TickerReader = reader
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("GET", "/bars/someticker?start=2016-12-08&end=2016-12-08", nil))
+		var bars []map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &bars); err != nil || len(bars) != 1 || bars[0]["date"] != "2016-12-08" {
+			t.Log("TestServerRangeInReaderLocation failed in ", loc, ". Result was: ", recorder.Body.String(), " Error: ", err)
+			t.Fail()
+		}
+	}
+}
+
+func TestServerConditionalRequests(t *testing.T) {
+	server := getTestServer()
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", "/bars/someticker", nil))
+	etag := recorder.Header().Get("ETag")
+	lastModified := recorder.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Log("TestServerConditionalRequests failed to set ETag and Last-Modified. Headers were: ", recorder.Header())
+		t.Fail()
+	}
+	testCases := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"'Matching ETag'", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"'Matching ETag in a list'", map[string]string{"If-None-Match": `"other", ` + etag}, http.StatusNotModified},
+		{"'Different ETag'", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"'Not modified since'", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"'Modified since'", map[string]string{"If-Modified-Since": time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)}, http.StatusOK},
+		{"'Different ETag but not modified since'", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified}, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		request := httptest.NewRequest("GET", "/bars/someticker", nil)
+		for header, value := range tc.headers {
+			request.Header.Set(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != tc.expectedStatus || (recorder.Code == http.StatusNotModified && recorder.Header().Get("ETag") != etag) {
+			t.Log("TestServerConditionalRequests test case ", tc.name, " failed. Result was: ", recorder.Code, " with ETag ", recorder.Header().Get("ETag"), " but should be: ", tc.expectedStatus)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package marketdata
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TickData holds trades. Precision is the price precision like the one of TickerData,
+// which the bars built from the trades inherit.
+type TickData struct {
+	Date       []time.Time
+	Price      []float64
+	Size       []int64
+	Conditions []string
+	Precision  *int
+}
+
+type QuoteData struct {
+	Date    []time.Time
+	Bid     []float64
+	Ask     []float64
+	BidSize []int64
+	AskSize []int64
+}
+
+type barState struct {
+	date     time.Time
+	open     float64
+	high     float64
+	low      float64
+	close    float64
+	volume   int64
+	notional float64
+	count    int32
+}
+
+func ReadTickData(dataReader DataReader, symbol string, tickConfig *ReadConfig) (TickData, error) {
+	return dataReader.readTickData(symbol, tickConfig)
+}
+
+func ReadQuoteData(dataReader DataReader, symbol string, quoteConfig *ReadConfig) (QuoteData, error) {
+	return dataReader.readQuoteData(symbol, quoteConfig)
+}
+
+func BuildTimeBars(ticks *TickData, interval time.Duration, includeVwap bool) (TickerData, error) {
+	if interval <= 0 {
+		return TickerData{}, errors.New("Bar interval must be greater than zero.")
+	}
+	return buildBars(ticks, includeVwap, func(bar *barState, i int) bool {
+		return bar.count == 0 || !truncateInLocation(ticks.Date[i], interval).Equal(bar.date)
+	}, func(i int) time.Time {
+		return truncateInLocation(ticks.Date[i], interval)
+	}), nil
+}
+
+// truncateInLocation truncates the wall clock time of date in its location, so daily
+// bars start at midnight of the exchange instead of midnight UTC.
+func truncateInLocation(date time.Time, interval time.Duration) time.Time {
+	_, offset := date.Zone()
+	shift := time.Duration(offset) * time.Second
+	return date.Add(shift).Truncate(interval).Add(-shift)
+}
+
+func BuildTickBars(ticks *TickData, ticksPerBar int, includeVwap bool) (TickerData, error) {
+	if ticksPerBar <= 0 {
+		return TickerData{}, errors.New("Ticks per bar must be greater than zero.")
+	}
+	return buildBars(ticks, includeVwap, func(bar *barState, i int) bool {
+		return bar.count == 0 || int(bar.count) >= ticksPerBar
+	}, nil), nil
+}
+
+func BuildVolumeBars(ticks *TickData, volumePerBar int64, includeVwap bool) (TickerData, error) {
+	if volumePerBar <= 0 {
+		return TickerData{}, errors.New("Volume per bar must be greater than zero.")
+	}
+	return buildBars(ticks, includeVwap, func(bar *barState, i int) bool {
+		return bar.count == 0 || bar.volume >= volumePerBar
+	}, nil), nil
+}
+
+func BuildDollarBars(ticks *TickData, dollarsPerBar float64, includeVwap bool) (TickerData, error) {
+	if dollarsPerBar <= 0 {
+		return TickerData{}, errors.New("Dollars per bar must be greater than zero.")
+	}
+	return buildBars(ticks, includeVwap, func(bar *barState, i int) bool {
+		return bar.count == 0 || bar.notional >= dollarsPerBar
+	}, nil), nil
+}
+
+func buildBars(ticks *TickData, includeVwap bool, isNewBar func(bar *barState, i int) bool, barDate func(i int) time.Time) TickerData {
+	var td TickerData
+	fields := map[string]int{"id": 0, "date": 1, "open": 2, "high": 3, "low": 4, "close": 5, "volume": 6}
+	if includeVwap {
+		fields["vwap"] = 7
+	}
+	td.initialize(fields, 0)
+	td.Precision = ticks.Precision
+	var bar barState
+	l := len(ticks.Date)
+	for i := 0; i < l; i++ {
+		if isNewBar(&bar, i) {
+			if bar.count > 0 {
+				td.appendBar(&bar)
+			}
+			bar = barState{}
+			bar.date = ticks.Date[i]
+			if barDate != nil {
+				bar.date = barDate(i)
+			}
+		}
+		bar.add(ticks.Price[i], ticks.Size[i])
+	}
+	if bar.count > 0 {
+		td.appendBar(&bar)
+	}
+	return td
+}
+
+func (bar *barState) add(price float64, size int64) {
+	if bar.count == 0 {
+		bar.open = price
+		bar.high = price
+		bar.low = price
+	}
+	if price > bar.high {
+		bar.high = price
+	}
+	if price < bar.low {
+		bar.low = price
+	}
+	bar.close = price
+	bar.volume = bar.volume + size
+	bar.notional = bar.notional + price*float64(size)
+	bar.count++
+}
+
+func (bar *barState) vwap() float64 {
+	if bar.volume == 0 {
+		return bar.close
+	}
+	return bar.notional / float64(bar.volume)
+}
+
+func (td *TickerData) appendBar(bar *barState) {
+	if td.Id != nil {
+		td.Id = append(td.Id, int32(len(td.Date)))
+	}
+	td.Date = append(td.Date, bar.date)
+	td.Open = append(td.Open, bar.open)
+	td.High = append(td.High, bar.high)
+	td.Low = append(td.Low, bar.low)
+	td.Close = append(td.Close, bar.close)
+	td.Volume = append(td.Volume, bar.volume)
+	if td.Vwap != nil {
+		td.Vwap = append(td.Vwap, roundPlus(bar.vwap(), td.pricePrecision()))
+	}
+}
+
+func (tick *TickData) initialize(size int) {
+	tick.Date = make([]time.Time, size)
+	tick.Price = make([]float64, size)
+	tick.Size = make([]int64, size)
+	tick.Conditions = make([]string, size)
+}
+
+func (tick *TickData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string, loc *time.Location) error {
+	var err error
+	for key, value := range fieldIndex {
+		if key == "date" || key == "timestamp" {
+			tick.Date[index], err = parseDate(dateFormat, strings.TrimSpace(data[value]), loc)
+			if err != nil {
+				return err
+			}
+		} else if key == "price" {
+			tick.Price[index], err = strconv.ParseFloat(strings.TrimSpace(data[value]), 64)
+			if err != nil {
+				return err
+			}
+		} else if key == "size" {
+			tick.Size[index], err = strconv.ParseInt(strings.TrimSpace(data[value]), 10, 64)
+			if err != nil {
+				return err
+			}
+		} else if key == "conditions" {
+			tick.Conditions[index] = strings.TrimSpace(data[value])
+		}
+	}
+	return nil
+}
+
+func (quote *QuoteData) initialize(size int) {
+	quote.Date = make([]time.Time, size)
+	quote.Bid = make([]float64, size)
+	quote.Ask = make([]float64, size)
+	quote.BidSize = make([]int64, size)
+	quote.AskSize = make([]int64, size)
+}
+
+func (quote *QuoteData) addFromRecords(data []string, fieldIndex map[string]int, index int, dateFormat string, loc *time.Location) error {
+	var err error
+	for key, value := range fieldIndex {
+		if key == "date" || key == "timestamp" {
+			quote.Date[index], err = parseDate(dateFormat, strings.TrimSpace(data[value]), loc)
+		} else if key == "bid" {
+			quote.Bid[index], err = strconv.ParseFloat(strings.TrimSpace(data[value]), 64)
+		} else if key == "ask" {
+			quote.Ask[index], err = strconv.ParseFloat(strings.TrimSpace(data[value]), 64)
+		} else if key == "bid_size" {
+			quote.BidSize[index], err = strconv.ParseInt(strings.TrimSpace(data[value]), 10, 64)
+		} else if key == "ask_size" {
+			quote.AskSize[index], err = strconv.ParseInt(strings.TrimSpace(data[value]), 10, 64)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (quote *QuoteData) MidPrice() []float64 {
+	mid := make([]float64, len(quote.Date))
+	for i := range quote.Date {
+		mid[i] = (quote.Bid[i] + quote.Ask[i]) / 2
+	}
+	return mid
+}
@@ -0,0 +1,101 @@
+package marketdata
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadTickAndQuoteData(t *testing.T) {
+	var csvReader CsvReader
+	csvReader.DataPath = "." + string(os.PathSeparator) + "testdata" + string(os.PathSeparator) + "tick"
+	csvReader.FileNamePattern = "{ticker}-{timeframe}.csv"
+	csvReader.DateFormat = time.RFC3339
+	ticks, err := ReadTickData(csvReader, "someticker", &ReadConfig{TimeFrame: "tick"})
+	expectedConditions := []string{"@", "@", "@ F", "@", "@", "@ T"}
+	if err != nil || len(ticks.Date) != 6 || ticks.Price[2] != 99.75 || ticks.Size[3] != 300 || !reflect.DeepEqual(ticks.Conditions, expectedConditions) {
+		t.Log("Failed to read TickData. Result was: ", ticks, " Error: ", err)
+		t.Fail()
+	}
+	quotes, err := ReadQuoteData(csvReader, "someticker", &ReadConfig{TimeFrame: "quote"})
+	expectedMid := []float64{100, 100.5}
+	if err != nil || !reflect.DeepEqual(quotes.MidPrice(), expectedMid) || quotes.AskSize[1] != 400 {
+		t.Log("Failed to read QuoteData. Result was: ", quotes, " Error: ", err)
+		t.Fail()
+	}
+	_, err = ReadTickData(csvReader, "someticker", &ReadConfig{TimeFrame: "quote"})
+	if err == nil {
+		t.Log("Reading quotes as ticks should fail with a missing header error.")
+		t.Fail()
+	}
+}
+
+func TestBuildBars(t *testing.T) {
+	var ticks TickData
+	ticks.Date = createDates([]string{"2017-01-03T09:30:00Z", "2017-01-03T09:30:20Z", "2017-01-03T09:30:40Z", "2017-01-03T09:31:05Z",
+		"2017-01-03T09:31:50Z", "2017-01-03T09:33:10Z"}, time.RFC3339)
+	ticks.Price = []float64{100.00, 100.50, 99.75, 100.25, 101.00, 100.75}
+	ticks.Size = []int64{100, 200, 100, 300, 100, 200}
+	ticks.Precision = NewPrecision(4)
+	minuteBars, _ := BuildTimeBars(&ticks, time.Minute, true)
+	tickBars, _ := BuildTickBars(&ticks, 4, false)
+	volumeBars, _ := BuildVolumeBars(&ticks, 400, false)
+	dollarBars, _ := BuildDollarBars(&ticks, 40000, false)
+	testCases := []struct {
+		name           string
+		result         TickerData
+		expectedDates  []string
+		expectedOpen   []float64
+		expectedHigh   []float64
+		expectedLow    []float64
+		expectedClose  []float64
+		expectedVolume []int64
+	}{
+		{"'Time bars'", minuteBars, []string{"2017-01-03T09:30:00Z", "2017-01-03T09:31:00Z", "2017-01-03T09:33:00Z"},
+			[]float64{100, 100.25, 100.75}, []float64{100.5, 101, 100.75}, []float64{99.75, 100.25, 100.75}, []float64{99.75, 101, 100.75}, []int64{400, 400, 200}},
+		{"'Tick bars'", tickBars, []string{"2017-01-03T09:30:00Z", "2017-01-03T09:31:50Z"},
+			[]float64{100, 101}, []float64{100.5, 101}, []float64{99.75, 100.75}, []float64{100.25, 100.75}, []int64{700, 300}},
+		{"'Volume bars'", volumeBars, []string{"2017-01-03T09:30:00Z", "2017-01-03T09:31:05Z", "2017-01-03T09:33:10Z"},
+			[]float64{100, 100.25, 100.75}, []float64{100.5, 101, 100.75}, []float64{99.75, 100.25, 100.75}, []float64{99.75, 101, 100.75}, []int64{400, 400, 200}},
+		{"'Dollar bars'", dollarBars, []string{"2017-01-03T09:30:00Z", "2017-01-03T09:31:05Z", "2017-01-03T09:33:10Z"},
+			[]float64{100, 100.25, 100.75}, []float64{100.5, 101, 100.75}, []float64{99.75, 100.25, 100.75}, []float64{99.75, 101, 100.75}, []int64{400, 400, 200}},
+	}
+	for _, tc := range testCases {
+		if !reflect.DeepEqual(tc.result.Date, createDates(tc.expectedDates, time.RFC3339)) || !reflect.DeepEqual(tc.result.Open, tc.expectedOpen) ||
+			!reflect.DeepEqual(tc.result.High, tc.expectedHigh) || !reflect.DeepEqual(tc.result.Low, tc.expectedLow) ||
+			!reflect.DeepEqual(tc.result.Close, tc.expectedClose) || !reflect.DeepEqual(tc.result.Volume, tc.expectedVolume) {
+			t.Log("TestBuildBars test case ", tc.name, " failed. Result was: ", tc.result)
+			t.Fail()
+		}
+	}
+	expectedVwap := []float64{100.1875, 100.4375, 100.75}
+	if !reflect.DeepEqual(minuteBars.Vwap, expectedVwap) || !reflect.DeepEqual(minuteBars.Id, []int32{0, 1, 2}) {
+		t.Log("TestBuildBars failed to compute vwap. Result was: ", minuteBars.Vwap, " but should be: ", expectedVwap)
+		t.Fail()
+	}
+	if tickBars.Vwap != nil {
+		t.Log("TestBuildBars should not compute vwap unless requested.")
+		t.Fail()
+	}
+	ticks.Precision = nil
+	minuteBars, _ = BuildTimeBars(&ticks, time.Minute, true)
+	if expectedVwap = []float64{100.19, 100.44, 100.75}; !reflect.DeepEqual(minuteBars.Vwap, expectedVwap) {
+		t.Log("TestBuildBars failed to round vwap to the default precision. Result was: ", minuteBars.Vwap, " but should be: ", expectedVwap)
+		t.Fail()
+	}
+}
+
+func TestBuildTimeBarsInLocation(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+	var ticks TickData
+	ticks.Date = []time.Time{time.Date(2017, 1, 3, 20, 0, 0, 0, est), time.Date(2017, 1, 4, 9, 30, 0, 0, est)}
+	ticks.Price = []float64{100, 101}
+	ticks.Size = []int64{100, 100}
+	result, err := BuildTimeBars(&ticks, 24*time.Hour, false)
+	expectedDates := []time.Time{time.Date(2017, 1, 3, 0, 0, 0, 0, est), time.Date(2017, 1, 4, 0, 0, 0, 0, est)}
+	if err != nil || len(result.Date) != len(expectedDates) || !result.Date[0].Equal(expectedDates[0]) || !result.Date[1].Equal(expectedDates[1]) {
+		t.Log("TestBuildTimeBarsInLocation failed to start daily bars at midnight of the location. Result was: ", result.Date, " but should be: ", expectedDates, " Error: ", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,52 @@
+package marketdata
+
+import (
+	"math"
+	"time"
+)
+
+type ValidationIssue struct {
+	Index   int
+	Date    time.Time
+	Message string
+}
+
+// ValidateTickerData checks that dates are strictly ascending and that every bar has
+// finite, positive prices consistent with its high and low and a non-negative volume.
+func ValidateTickerData(td *TickerData) []ValidationIssue {
+	issues := []ValidationIssue{}
+	l := len(td.Date)
+	for i := 0; i < l; i++ {
+		add := func(message string) {
+			issues = append(issues, ValidationIssue{i, td.Date[i], message})
+		}
+		if i > 0 && !td.Date[i].After(td.Date[i-1]) {
+			if td.Date[i].Equal(td.Date[i-1]) {
+				add("Duplicate date.")
+			} else {
+				add("Date is not in ascending order.")
+			}
+		}
+		if td.Open == nil || td.High == nil || td.Low == nil || td.Close == nil {
+			continue
+		}
+		prices := []float64{td.Open[i], td.High[i], td.Low[i], td.Close[i]}
+		valid := true
+		for _, price := range prices {
+			if math.IsNaN(price) || math.IsInf(price, 0) || price <= 0 {
+				valid = false
+			}
+		}
+		if !valid {
+			add("Prices must be finite and greater than zero.")
+		} else if td.High[i] < math.Max(td.Open[i], td.Close[i]) || td.High[i] < td.Low[i] {
+			add("High is below the open, close or low.")
+		} else if td.Low[i] > math.Min(td.Open[i], td.Close[i]) {
+			add("Low is above the open or close.")
+		}
+		if td.Volume != nil && td.Volume[i] < 0 {
+			add("Volume is negative.")
+		}
+	}
+	return issues
+}
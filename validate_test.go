@@ -0,0 +1,28 @@
+package marketdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateTickerData(t *testing.T) {
+	td := getExpectedDailyData()
+	if issues := ValidateTickerData(&td); len(issues) != 0 {
+		t.Log("TestValidateTickerData failed for valid data. Result was: ", issues)
+		t.Fail()
+	}
+	td.Date[2] = td.Date[1]
+	td.High[5] = td.Low[5] - 1
+	td.Low[7] = td.Close[7] + 1
+	td.Volume[9] = -1
+	td.Open[11] = 0
+	var indexes []int
+	for _, issue := range ValidateTickerData(&td) {
+		indexes = append(indexes, issue.Index)
+	}
+	expectedIndexes := []int{2, 5, 7, 9, 11}
+	if !reflect.DeepEqual(indexes, expectedIndexes) {
+		t.Log("TestValidateTickerData failed. Result was: ", indexes, " but should be: ", expectedIndexes)
+		t.Fail()
+	}
+}